@@ -0,0 +1,134 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// goipc is a tool for inspecting and managing go-ipc objects.
+// It can be used to debug applications, which use go-ipc, on production hosts.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/nxgtw/go-ipc/internal/common"
+	"github.com/nxgtw/go-ipc/shm"
+)
+
+var (
+	objType  = flag.String("type", "", "object type. for mq: default | fast | linux | sysv, for sync: m | spin | rw | sem")
+	timeout  = flag.Duration("timeout", -1, "timeout for mq send/recv. negative values mean no timeout")
+	prio     = flag.Int("prio", 0, "priority of a message for mq send")
+	msgSize  = flag.Int("size", 65536, "size of the receive buffer for mq recv")
+	hexData  = flag.Bool("hex", false, "pass and print messages as hex strings")
+	useStats = flag.Bool("stats", false, "show shared lock statistics for sync stat. creates them, if they do not exist")
+	ns       = flag.String("ns", "", "namespace of the objects. overrides "+common.NamespaceEnv)
+)
+
+const usage = `  goipc is a tool for inspecting and managing go-ipc objects.
+usage:
+  goipc [options] shm|mq|sync command [arguments]
+shm commands:
+  ls
+    lists shared memory objects and go-ipc objects they belong to (linux only)
+  cat name [offset [length]]
+    writes the contents of a shared memory object to stdout
+  hexdump name [offset [length]]
+    prints the contents of a shared memory object in hex
+  rm name...
+    removes shared memory objects
+mq commands:
+  stat name
+    prints the capacity and the length of a queue
+  send name {message}
+    sends a message. if the message is '-', it is read from stdin
+  recv name
+    receives a message and writes it to stdout
+  peek name
+    writes the first message of a queue to stdout without receiving it (fast mq only)
+  purge name
+    removes all messages from a queue
+sync commands:
+  stat name
+    prints the state of a mutex or a semaphore without locking it, its statistics,
+    and, with ipc_debug, its holders
+  unlock name
+    unlocks a mutex, whose owner has crashed. for rw mutexes it releases the write lock,
+    for semaphores it signals once
+options:
+`
+
+func runCommand() error {
+	args := flag.Args()
+	switch args[0] {
+	case "shm":
+		return runShmCommand(args[1:])
+	case "mq":
+		return runMqCommand(args[1:])
+	case "sync":
+		return runSyncCommand(args[1:])
+	default:
+		return fmt.Errorf("unknown command group %q", args[0])
+	}
+}
+
+// checkArgs checks, that the command args[0] has from min to max arguments. max < 0 means no upper limit.
+func checkArgs(args []string, min, max int) error {
+	if n := len(args) - 1; n < min || (max >= 0 && n > max) {
+		return fmt.Errorf("%s: invalid number of arguments", args[0])
+	}
+	return nil
+}
+
+// parseRange parses optional offset and length arguments.
+func parseRange(args []string, size int64) (int64, int, error) {
+	var offset, length int64
+	var err error
+	if len(args) > 0 {
+		if offset, err = strconv.ParseInt(args[0], 0, 64); err != nil {
+			return 0, 0, err
+		}
+	}
+	if offset < 0 || offset > size {
+		return 0, 0, fmt.Errorf("offset %d is out of range [0, %d]", offset, size)
+	}
+	length = size - offset
+	if len(args) > 1 {
+		if length, err = strconv.ParseInt(args[1], 0, 64); err != nil {
+			return 0, 0, err
+		}
+		if length < 0 || offset+length > size {
+			return 0, 0, fmt.Errorf("length %d is out of range [0, %d]", length, size-offset)
+		}
+	}
+	return offset, int(length), nil
+}
+
+func formatAge(modTime time.Time) string {
+	if modTime.IsZero() {
+		return "-"
+	}
+	return (time.Since(modTime) / time.Second * time.Second).String()
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if len(*ns) > 0 {
+		if err := shm.SetNamespace(*ns); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := runCommand(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// captureOutput runs f and returns everything it has written to stdout.
+func captureOutput(f func() error) (string, error) {
+	file, err := ioutil.TempFile("", "goipc-output")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+	stdout := os.Stdout
+	os.Stdout = file
+	err = f()
+	os.Stdout = stdout
+	if err != nil {
+		return "", err
+	}
+	data, err := ioutil.ReadFile(file.Name())
+	return string(data), err
+}
+
+func TestCheckArgs(t *testing.T) {
+	a := assert.New(t)
+	a.NoError(checkArgs([]string{"cat", "name"}, 1, 3))
+	a.Error(checkArgs([]string{"cat"}, 1, 3))
+	a.Error(checkArgs([]string{"cat", "name", "0", "1", "2"}, 1, 3))
+	a.NoError(checkArgs([]string{"rm", "a", "b", "c", "d"}, 1, -1))
+}
+
+func TestParseRange(t *testing.T) {
+	a := assert.New(t)
+	offset, length, err := parseRange(nil, 16)
+	a.NoError(err)
+	a.Equal(int64(0), offset)
+	a.Equal(16, length)
+	offset, length, err = parseRange([]string{"0x4"}, 16)
+	a.NoError(err)
+	a.Equal(int64(4), offset)
+	a.Equal(12, length)
+	offset, length, err = parseRange([]string{"4", "8"}, 16)
+	a.NoError(err)
+	a.Equal(int64(4), offset)
+	a.Equal(8, length)
+	_, _, err = parseRange([]string{"17"}, 16)
+	a.Error(err)
+	_, _, err = parseRange([]string{"4", "13"}, 16)
+	a.Error(err)
+	_, _, err = parseRange([]string{"x"}, 16)
+	a.Error(err)
+}
@@ -0,0 +1,211 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	ipc "github.com/nxgtw/go-ipc"
+	"github.com/nxgtw/go-ipc/mq"
+
+	"github.com/pkg/errors"
+)
+
+type lenCapper interface {
+	Len() int
+	Cap() int
+}
+
+func runMqCommand(args []string) error {
+	if err := checkArgs(args, 1, 2); err != nil {
+		return err
+	}
+	name := args[1]
+	switch args[0] {
+	case "stat":
+		return mqStat(name)
+	case "send":
+		if err := checkArgs(args, 2, 2); err != nil {
+			return err
+		}
+		return mqSend(name, args[2])
+	case "recv":
+		return mqReceive(name, false)
+	case "peek":
+		return mqReceive(name, true)
+	case "purge":
+		return mqPurge(name)
+	default:
+		return fmt.Errorf("unknown mq command %q", args[0])
+	}
+}
+
+func mqStat(name string) error {
+	if *objType == "sysv" {
+		return sysvMqStat(name)
+	}
+	m, err := openMq(name, *objType, mq.O_NONBLOCK)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	if _, ok := m.(*mq.FastMq); ok {
+		_, maxMsgSize, err := mq.FastMqAttrs(name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("max message size: %d\n", maxMsgSize)
+	}
+	if lc, ok := m.(lenCapper); ok {
+		fmt.Printf("capacity: %d\nlength: %d\n", lc.Cap(), lc.Len())
+	}
+	return nil
+}
+
+// sysvMqStat finds a SysV queue in the inventory, as the queue does not report its state.
+func sysvMqStat(name string) error {
+	inv, err := ipc.ListObjects()
+	if err != nil {
+		return err
+	}
+	for _, o := range inv.Objects {
+		if o.Type != ipc.SysVMessageQueueType || o.Name != name {
+			continue
+		}
+		for _, r := range o.Resources {
+			if r.Kind == ipc.SysVMessageQueueResource {
+				fmt.Printf("id: %d\nkey: %#x\nbytes: %d\nmode: %v\nage: %s\n", r.ID, r.Key, r.Size, r.Mode, formatAge(r.ModTime))
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("sysv queue %q not found", name)
+}
+
+func mqSend(name, message string) error {
+	data := []byte(message)
+	if message == "-" {
+		input, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		data = input
+	}
+	if *hexData {
+		decoded, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			return err
+		}
+		data = decoded
+	}
+	m, err := openMq(name, *objType, 0)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	switch {
+	case *prio != 0 && *timeout >= 0:
+		return errors.New("-prio and -timeout can't be used together")
+	case *prio != 0:
+		pm, ok := m.(mq.PriorityMessenger)
+		if !ok {
+			return errors.New("the queue does not support priorities")
+		}
+		return pm.SendPriority(data, *prio)
+	case *timeout >= 0:
+		tm, ok := m.(mq.TimedMessenger)
+		if !ok {
+			return errors.New("the queue does not support timeouts")
+		}
+		return tm.SendTimeout(data, *timeout)
+	default:
+		return m.Send(data)
+	}
+}
+
+func mqReceive(name string, peek bool) error {
+	m, err := openMq(name, *objType, 0)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	if fmq, ok := m.(*mq.FastMq); ok {
+		if _, *msgSize, err = mq.FastMqAttrs(name); err != nil {
+			return err
+		}
+		if peek {
+			data := make([]byte, *msgSize)
+			l, _, err := fmq.Peek(data)
+			if err != nil {
+				return err
+			}
+			return printMessage(data[:l])
+		}
+	} else if peek {
+		return errors.New("peek is supported for fast mq only")
+	}
+	data := make([]byte, *msgSize)
+	var l int
+	if *timeout >= 0 {
+		tm, ok := m.(mq.TimedMessenger)
+		if !ok {
+			return errors.New("the queue does not support timeouts")
+		}
+		l, err = tm.ReceiveTimeout(data, *timeout)
+	} else {
+		l, err = m.Receive(data)
+	}
+	if err != nil {
+		return err
+	}
+	return printMessage(data[:l])
+}
+
+func mqPurge(name string) error {
+	m, err := openMq(name, *objType, mq.O_NONBLOCK)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	if b, ok := m.(mq.Blocker); ok {
+		if err = b.SetBlocking(false); err != nil {
+			return err
+		}
+	}
+	if _, ok := m.(*mq.FastMq); ok {
+		if _, *msgSize, err = mq.FastMqAttrs(name); err != nil {
+			return err
+		}
+	}
+	data := make([]byte, *msgSize)
+	var count int
+	for {
+		if _, err = m.Receive(data); err != nil {
+			break
+		}
+		count++
+	}
+	if !isEmptyQueueErr(err) {
+		return err
+	}
+	fmt.Printf("%d messages removed\n", count)
+	return nil
+}
+
+func printMessage(data []byte) error {
+	if *hexData {
+		_, err := fmt.Println(hex.EncodeToString(data))
+		return err
+	}
+	_, err := os.Stdout.Write(data)
+	return err
+}
+
+// isEmptyQueueErr returns true, if a non-blocking receive failed, because the queue is empty.
+func isEmptyQueueErr(err error) bool {
+	return mq.IsTemporary(err) || mq.IsTemporary(errors.Cause(err)) || isNoMessageErr(errors.Cause(err))
+}
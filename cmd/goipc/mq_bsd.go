@@ -0,0 +1,24 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build darwin freebsd
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/nxgtw/go-ipc/mq"
+)
+
+func openMq(name, typ string, flags int) (mq.Messenger, error) {
+	switch typ {
+	case "", "default":
+		return mq.Open(name, flags)
+	case "fast":
+		return mq.OpenFastMq(name, flags)
+	case "sysv":
+		return mq.OpenSystemVMessageQueue(name, flags)
+	default:
+		return nil, fmt.Errorf("unknown mq type %q", typ)
+	}
+}
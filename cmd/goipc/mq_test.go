@@ -0,0 +1,80 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/nxgtw/go-ipc/mq"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testMqName = "go-ipc.goipc-test.mq"
+
+func TestMqFast(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(mq.DestroyFastMq(testMqName)) {
+		return
+	}
+	m, err := mq.CreateFastMq(testMqName, 0, 0666, 4, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.DestroyFastMq(testMqName)
+	defer m.Close()
+	typ := *objType
+	*objType = "fast"
+	defer func() {
+		*objType = typ
+	}()
+	a.NoError(runMqCommand([]string{"send", testMqName, "hello"}))
+	a.NoError(runMqCommand([]string{"send", testMqName, "world"}))
+	out, err := captureOutput(func() error {
+		return runMqCommand([]string{"stat", testMqName})
+	})
+	a.NoError(err)
+	a.Equal("max message size: 16\ncapacity: 4\nlength: 2\n", out)
+	out, err = captureOutput(func() error {
+		return runMqCommand([]string{"peek", testMqName})
+	})
+	a.NoError(err)
+	a.Equal("hello", out)
+	out, err = captureOutput(func() error {
+		return runMqCommand([]string{"recv", testMqName})
+	})
+	a.NoError(err)
+	a.Equal("hello", out)
+	out, err = captureOutput(func() error {
+		return runMqCommand([]string{"purge", testMqName})
+	})
+	a.NoError(err)
+	a.Equal("1 messages removed\n", out)
+	a.Equal(0, m.Len())
+}
+
+func TestMqHex(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(mq.DestroyFastMq(testMqName)) {
+		return
+	}
+	m, err := mq.CreateFastMq(testMqName, 0, 0666, 4, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.DestroyFastMq(testMqName)
+	defer m.Close()
+	typ, hex := *objType, *hexData
+	*objType, *hexData = "fast", true
+	defer func() {
+		*objType, *hexData = typ, hex
+	}()
+	a.NoError(runMqCommand([]string{"send", testMqName, "00ff10"}))
+	a.Error(runMqCommand([]string{"send", testMqName, "xyz"}))
+	out, err := captureOutput(func() error {
+		return runMqCommand([]string{"recv", testMqName})
+	})
+	a.NoError(err)
+	a.Equal("00ff10\n", out)
+	a.Error(runMqCommand([]string{"unknown", testMqName}))
+}
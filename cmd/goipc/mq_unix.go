@@ -0,0 +1,19 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build darwin freebsd linux
+
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// isNoMessageErr returns true, if a non-blocking receive from a SysV queue failed, because the queue is empty.
+func isNoMessageErr(err error) bool {
+	if sysErr, ok := err.(*os.SyscallError); ok {
+		return sysErr.Err == unix.ENOMSG
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/nxgtw/go-ipc/mq"
+)
+
+func openMq(name, typ string, flags int) (mq.Messenger, error) {
+	switch typ {
+	case "", "default", "fast":
+		return mq.OpenFastMq(name, flags)
+	default:
+		return nil, fmt.Errorf("unknown mq type %q", typ)
+	}
+}
+
+func isNoMessageErr(err error) bool {
+	return false
+}
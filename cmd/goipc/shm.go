@@ -0,0 +1,110 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	ipc "github.com/nxgtw/go-ipc"
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
+)
+
+func runShmCommand(args []string) error {
+	switch args[0] {
+	case "ls":
+		if err := checkArgs(args, 0, 0); err != nil {
+			return err
+		}
+		return shmList()
+	case "cat":
+		if err := checkArgs(args, 1, 3); err != nil {
+			return err
+		}
+		return shmRead(args[1], args[2:], false)
+	case "hexdump":
+		if err := checkArgs(args, 1, 3); err != nil {
+			return err
+		}
+		return shmRead(args[1], args[2:], true)
+	case "rm":
+		if err := checkArgs(args, 1, -1); err != nil {
+			return err
+		}
+		for _, name := range args[1:] {
+			if err := shm.DestroyMemoryObject(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown shm command %q", args[0])
+	}
+}
+
+func shmList() error {
+	inv, err := ipc.ListObjects()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSIZE\tMODE\tUID\tAGE\tOBJECT\tTYPE\tPIDS")
+	for _, o := range inv.Objects {
+		for _, r := range o.Resources {
+			if r.Kind != ipc.SharedMemoryResource {
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%d\t%v\t%d\t%s\t%s\t%s\t%v\n", r.Name, r.Size, r.Mode, r.UID, formatAge(r.ModTime), o.Name, o.Type, r.Pids)
+		}
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if inv.Uninspected > 0 {
+		fmt.Fprintf(os.Stderr, "%d processes could not be inspected, pids may be incomplete\n", inv.Uninspected)
+	}
+	return nil
+}
+
+func shmRead(name string, args []string, dump bool) error {
+	obj, err := shm.NewMemoryObject(name, os.O_RDONLY, 0666)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+	offset, length, err := parseRange(args, obj.Size())
+	if err != nil || length == 0 {
+		return err
+	}
+	region, err := mmf.NewMemoryRegion(obj, mmf.MEM_READ_ONLY, offset, length)
+	if err != nil {
+		return err
+	}
+	defer region.Close()
+	if dump {
+		return hexDump(os.Stdout, region.Data(), offset)
+	}
+	_, err = os.Stdout.Write(region.Data())
+	return err
+}
+
+// hexDump prints data in the format of hex.Dump, using real offsets of the bytes.
+func hexDump(w io.Writer, data []byte, offset int64) error {
+	for len(data) > 0 {
+		line := data
+		if len(line) > 16 {
+			line = line[:16]
+		}
+		// hex.Dump starts with an 8-digit offset, which is replaced with the real one.
+		if _, err := fmt.Fprintf(w, "%08x%s", offset, hex.Dump(line)[8:]); err != nil {
+			return err
+		}
+		offset += int64(len(line))
+		data = data[len(line):]
+	}
+	return nil
+}
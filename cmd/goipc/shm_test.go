@@ -0,0 +1,88 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testShmName = "go-ipc.goipc-test.shm"
+
+func createTestShm(a *assert.Assertions, data []byte) bool {
+	if !a.NoError(shm.DestroyMemoryObject(testShmName)) {
+		return false
+	}
+	obj, err := shm.NewMemoryObject(testShmName, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666)
+	if !a.NoError(err) {
+		return false
+	}
+	defer obj.Close()
+	if !a.NoError(obj.Truncate(int64(len(data)))) {
+		return false
+	}
+	region, err := mmf.NewMemoryRegion(obj, mmf.MEM_READWRITE, 0, len(data))
+	if !a.NoError(err) {
+		return false
+	}
+	defer region.Close()
+	copy(region.Data(), data)
+	return true
+}
+
+func TestShmCat(t *testing.T) {
+	a := assert.New(t)
+	if !createTestShm(a, []byte("hello, world")) {
+		return
+	}
+	defer shm.DestroyMemoryObject(testShmName)
+	out, err := captureOutput(func() error {
+		return runShmCommand([]string{"cat", testShmName})
+	})
+	a.NoError(err)
+	a.Equal("hello, world", out)
+	out, err = captureOutput(func() error {
+		return runShmCommand([]string{"cat", testShmName, "7", "5"})
+	})
+	a.NoError(err)
+	a.Equal("world", out)
+	_, err = captureOutput(func() error {
+		return runShmCommand([]string{"cat", testShmName, "7", "6"})
+	})
+	a.Error(err)
+}
+
+func TestShmHexdump(t *testing.T) {
+	a := assert.New(t)
+	data := make([]byte, 20)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	if !createTestShm(a, data) {
+		return
+	}
+	defer shm.DestroyMemoryObject(testShmName)
+	out, err := captureOutput(func() error {
+		return runShmCommand([]string{"hexdump", testShmName, "16"})
+	})
+	a.NoError(err)
+	a.Equal("00000010  10 11 12 13                                       |....|\n", out)
+}
+
+func TestShmRm(t *testing.T) {
+	a := assert.New(t)
+	if !createTestShm(a, []byte("data")) {
+		return
+	}
+	defer shm.DestroyMemoryObject(testShmName)
+	a.NoError(runShmCommand([]string{"rm", testShmName}))
+	_, err := shm.NewMemoryObject(testShmName, os.O_RDONLY, 0666)
+	a.Error(err)
+	a.Error(runShmCommand([]string{"rm"}))
+	a.Error(runShmCommand([]string{"unknown"}))
+}
@@ -0,0 +1,148 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	ipc_sync "github.com/nxgtw/go-ipc/sync"
+
+	"github.com/pkg/errors"
+)
+
+type statser interface {
+	Stats() (ipc_sync.Stats, error)
+}
+
+type locked interface {
+	Locked() bool
+}
+
+type rwStater interface {
+	State() (readers, writers int)
+}
+
+func runSyncCommand(args []string) error {
+	if err := checkArgs(args, 1, 1); err != nil {
+		return err
+	}
+	switch args[0] {
+	case "stat":
+		return syncStat(args[1])
+	case "unlock":
+		return syncUnlock(args[1])
+	default:
+		return fmt.Errorf("unknown sync command %q", args[0])
+	}
+}
+
+// openSyncObject opens an existing mutex or a semaphore.
+func openSyncObject(name, typ string) (io.Closer, error) {
+	switch typ {
+	case "", "m":
+		return ipc_sync.NewMutex(name, 0, 0666)
+	case "spin":
+		return ipc_sync.NewSpinMutex(name, 0, 0666)
+	case "rw":
+		return ipc_sync.NewRWMutex(name, 0, 0666)
+	case "sem":
+		return ipc_sync.NewSemaphore(name, 0, 0666, 0)
+	default:
+		return nil, fmt.Errorf("unknown sync object type %q", typ)
+	}
+}
+
+func syncStat(name string) error {
+	if *useStats {
+		ipc_sync.SetStatsEnabled(true)
+	}
+	obj, err := openSyncObject(name, *objType)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+	printSyncState(os.Stdout, obj)
+	if s, ok := obj.(statser); ok && *useStats {
+		stats, err := s.Stats()
+		if err != nil {
+			return err
+		}
+		printLockStats(os.Stdout, &stats.Shared)
+	}
+	if ipc_sync.DebugEnabled() {
+		holders, err := ipc_sync.DumpHolders()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "PID\tTID\tGOROUTINE\tSTATE\tSINCE")
+		for _, h := range holders {
+			if h.Lock != name {
+				continue
+			}
+			state := "holding"
+			if h.Waiting {
+				state = "waiting"
+			}
+			fmt.Fprintf(w, "%d\t%d\t%d\t%s\t%s\n", h.Pid, h.Tid, h.Goroutine, state, formatAge(h.Since))
+		}
+		return w.Flush()
+	}
+	return nil
+}
+
+// printSyncState prints the state of a lock. the state is read without locking,
+// so that the lock and its statistics are not changed.
+func printSyncState(w io.Writer, obj io.Closer) {
+	switch l := obj.(type) {
+	case locked:
+		state := "unlocked"
+		if l.Locked() {
+			state = "locked"
+		}
+		fmt.Fprintf(w, "state: %s\n", state)
+	case rwStater:
+		readers, writers := l.State()
+		fmt.Fprintf(w, "readers: %d\nwriters: %d\n", readers, writers)
+	}
+}
+
+func printLockStats(w io.Writer, stats *ipc_sync.LockStats) {
+	fmt.Fprintf(w, "acquisitions: %d\ncontended: %d\ntimeouts: %d\nwait time: %v\n",
+		stats.Acquisitions, stats.Contended, stats.Timeouts, stats.WaitTime)
+	fmt.Fprintln(w, "wait histogram:")
+	var lower time.Duration
+	for i, count := range stats.WaitHistogram {
+		if i < len(ipc_sync.StatsBucketBounds) {
+			fmt.Fprintf(w, "  [%v, %v): %d\n", lower, ipc_sync.StatsBucketBounds[i], count)
+			lower = ipc_sync.StatsBucketBounds[i]
+		} else {
+			fmt.Fprintf(w, "  [%v, inf): %d\n", lower, count)
+		}
+	}
+}
+
+// syncUnlock unlocks a lock, which was left locked by a crashed process.
+func syncUnlock(name string) (err error) {
+	obj, err := openSyncObject(name, *objType)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+	if sem, ok := obj.(*ipc_sync.Semaphore); ok {
+		sem.Signal(1)
+		return nil
+	}
+	// unlock of an unlocked mutex panics.
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("failed to unlock %q: %v", name, r)
+		}
+	}()
+	obj.(ipc_sync.IPCLocker).Unlock()
+	return nil
+}
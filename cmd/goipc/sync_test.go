@@ -0,0 +1,93 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package main
+
+import (
+	"os"
+	"testing"
+
+	ipc_sync "github.com/nxgtw/go-ipc/sync"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testMutexName = "go-ipc.goipc-test.mutex"
+
+func TestSyncStat(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(ipc_sync.DestroyMutex(testMutexName)) {
+		return
+	}
+	ipc_sync.SetStatsEnabled(true)
+	m, err := ipc_sync.NewMutex(testMutexName, os.O_CREATE|os.O_EXCL, 0666)
+	ipc_sync.SetStatsEnabled(false)
+	if !a.NoError(err) {
+		return
+	}
+	defer ipc_sync.DestroyMutex(testMutexName)
+	defer m.Close()
+	out, err := captureOutput(func() error {
+		return runSyncCommand([]string{"stat", testMutexName})
+	})
+	a.NoError(err)
+	a.Equal("state: unlocked\n", out)
+	m.Lock()
+	out, err = captureOutput(func() error {
+		return runSyncCommand([]string{"stat", testMutexName})
+	})
+	a.NoError(err)
+	a.Equal("state: locked\n", out)
+	m.Unlock()
+	// stat must not lock the mutex, so that its statistics are not changed.
+	if s, ok := m.(statser); ok {
+		stats, err := s.Stats()
+		if a.NoError(err) {
+			a.Equal(uint64(1), stats.Shared.Acquisitions)
+		}
+	}
+}
+
+func TestSyncStatRW(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(ipc_sync.DestroyRWMutex(testMutexName)) {
+		return
+	}
+	m, err := ipc_sync.NewRWMutex(testMutexName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer ipc_sync.DestroyRWMutex(testMutexName)
+	defer m.Close()
+	typ := *objType
+	*objType = "rw"
+	defer func() {
+		*objType = typ
+	}()
+	m.RLock()
+	m.RLock()
+	out, err := captureOutput(func() error {
+		return runSyncCommand([]string{"stat", testMutexName})
+	})
+	a.NoError(err)
+	a.Equal("readers: 2\nwriters: 0\n", out)
+	m.RUnlock()
+	m.RUnlock()
+}
+
+func TestSyncUnlock(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(ipc_sync.DestroyMutex(testMutexName)) {
+		return
+	}
+	m, err := ipc_sync.NewMutex(testMutexName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer ipc_sync.DestroyMutex(testMutexName)
+	defer m.Close()
+	m.Lock()
+	a.NoError(runSyncCommand([]string{"unlock", testMutexName}))
+	a.True(m.LockTimeout(0))
+	m.Unlock()
+	a.Error(runSyncCommand([]string{"unlock", testMutexName}))
+}
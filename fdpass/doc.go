@@ -0,0 +1,7 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// Package fdpass allows to pass ipc objects to other processes over unix domain sockets.
+// Objects are sent as descriptors with SCM_RIGHTS along with their type and metadata,
+// so that the processes do not need to agree on object names.
+// Shared memory objects, linux message queues, and eventfds are supported on linux.
+package fdpass
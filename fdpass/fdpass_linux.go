@@ -0,0 +1,325 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package fdpass
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/common"
+	"github.com/nxgtw/go-ipc/mq"
+	"github.com/nxgtw/go-ipc/shm"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// ObjectType is a type of an object, which is passed over a socket.
+type ObjectType uint8
+
+const (
+	// MemoryObjectType is a type of shm.MemoryObject.
+	MemoryObjectType ObjectType = iota + 1
+	// LinuxMessageQueueType is a type of mq.LinuxMessageQueue.
+	LinuxMessageQueueType
+	// EventFdType is a type of EventFd.
+	EventFdType
+)
+
+const (
+	// EFD_SEMAPHORE makes eventfd reads decrement the counter by 1 instead of resetting it.
+	EFD_SEMAPHORE = unix.EFD_SEMAPHORE
+	// EFD_NONBLOCK makes eventfd operations return an error instead of blocking.
+	EFD_NONBLOCK = unix.EFD_NONBLOCK
+)
+
+const (
+	// message is the following sequence:
+	//	magic (uint16) | type (uint8) | padding | size (int64) | name
+	headerSize     = 12
+	messageMagic   = 0x4950
+	maxNameLen     = 255
+	maxMessageSize = headerSize + maxNameLen
+	// if the peer sends more descriptors, than expected, they are received and closed.
+	maxFds = 16
+)
+
+type header struct {
+	typ  ObjectType
+	size int64
+	name string
+}
+
+func (h *header) encode() []byte {
+	result := make([]byte, headerSize+len(h.name))
+	binary.LittleEndian.PutUint16(result, messageMagic)
+	result[2] = byte(h.typ)
+	binary.LittleEndian.PutUint64(result[4:], uint64(h.size))
+	copy(result[headerSize:], h.name)
+	return result
+}
+
+func (h *header) decode(data []byte) error {
+	if len(data) < headerSize || binary.LittleEndian.Uint16(data) != messageMagic {
+		return errors.New("invalid message header")
+	}
+	h.typ = ObjectType(data[2])
+	h.size = int64(binary.LittleEndian.Uint64(data[4:]))
+	h.name = string(data[headerSize:])
+	return nil
+}
+
+// SendMemoryObject sends a memory object over a socket.
+// The receiving side gets an anonymous object of the same size,
+// which refers to the same memory. The object remains valid after the send.
+func SendMemoryObject(conn *net.UnixConn, obj *shm.MemoryObject) error {
+	return send(conn, int(obj.Fd()), &header{typ: MemoryObjectType, size: obj.Size()})
+}
+
+// SendLinuxMessageQueue sends a queue over a socket. The name of the queue is passed too,
+// so that the receiving side is able to destroy it. The queue remains valid after the send.
+func SendLinuxMessageQueue(conn *net.UnixConn, queue *mq.LinuxMessageQueue) error {
+	return send(conn, queue.ID(), &header{typ: LinuxMessageQueueType, size: int64(queue.Cap()), name: queue.Name()})
+}
+
+// SendEventFd sends an eventfd over a socket. The eventfd remains valid after the send.
+func SendEventFd(conn *net.UnixConn, ev *EventFd) error {
+	return send(conn, ev.Fd(), &header{typ: EventFdType})
+}
+
+// Receive receives an object sent with one of Send functions.
+// It returns *shm.MemoryObject, *mq.LinuxMessageQueue, or *EventFd.
+// Linux message queues are returned in blocking mode.
+// It returns io.EOF, if the connection was closed by the peer.
+func Receive(conn *net.UnixConn) (io.Closer, error) {
+	hdr, fd, err := receive(conn)
+	if err != nil {
+		return nil, err
+	}
+	result, err := newObject(hdr, fd, 0)
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	return result, nil
+}
+
+// ReceiveMemoryObject receives a memory object. It fails, if an object of another type was sent.
+// Destroy on the received object only closes it.
+func ReceiveMemoryObject(conn *net.UnixConn) (*shm.MemoryObject, error) {
+	obj, err := receiveType(conn, MemoryObjectType, 0)
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*shm.MemoryObject), nil
+}
+
+// ReceiveLinuxMessageQueue receives a linux message queue. It fails, if an object of another type was sent.
+//	flag - 0 or mq.O_NONBLOCK.
+func ReceiveLinuxMessageQueue(conn *net.UnixConn, flag int) (*mq.LinuxMessageQueue, error) {
+	obj, err := receiveType(conn, LinuxMessageQueueType, flag)
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*mq.LinuxMessageQueue), nil
+}
+
+// ReceiveEventFd receives an eventfd. It fails, if an object of another type was sent.
+func ReceiveEventFd(conn *net.UnixConn) (*EventFd, error) {
+	obj, err := receiveType(conn, EventFdType, 0)
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*EventFd), nil
+}
+
+func receiveType(conn *net.UnixConn, typ ObjectType, flag int) (io.Closer, error) {
+	hdr, fd, err := receive(conn)
+	if err != nil {
+		return nil, err
+	}
+	if hdr.typ != typ {
+		unix.Close(fd)
+		return nil, errors.Errorf("unexpected object type %d, %d was expected", hdr.typ, typ)
+	}
+	result, err := newObject(hdr, fd, flag)
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	return result, nil
+}
+
+// newObject creates an object of the given type, which takes the ownership of the descriptor.
+func newObject(hdr *header, fd int, flag int) (io.Closer, error) {
+	switch hdr.typ {
+	case MemoryObjectType:
+		var st unix.Stat_t
+		if err := unix.Fstat(fd, &st); err != nil {
+			return nil, errors.Wrap(os.NewSyscallError("fstat", err), "invalid descriptor")
+		}
+		// the memory beyond the end of the object can't be accessed,
+		// so the object must not be shrunk by the sender in meanwhile.
+		if st.Size < hdr.size {
+			return nil, errors.Errorf("memory object size is %d, %d was sent", st.Size, hdr.size)
+		}
+		return shm.NewMemoryObjectFromFd(uintptr(fd))
+	case LinuxMessageQueueType:
+		return mq.NewLinuxMessageQueueFromFd(fd, hdr.name, flag)
+	case EventFdType:
+		return NewEventFdFromFd(fd), nil
+	default:
+		return nil, errors.Errorf("unknown object type %d", hdr.typ)
+	}
+}
+
+func send(conn *net.UnixConn, fd int, hdr *header) error {
+	if len(hdr.name) > maxNameLen {
+		return errors.Errorf("object name is longer than %d bytes", maxNameLen)
+	}
+	data := hdr.encode()
+	n, _, err := conn.WriteMsgUnix(data, unix.UnixRights(fd), nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to send the object")
+	}
+	if n != len(data) {
+		return errors.Errorf("short write: %d of %d bytes were sent", n, len(data))
+	}
+	return nil
+}
+
+// receive reads a message with a single descriptor. the caller must close the descriptor.
+func receive(conn *net.UnixConn) (*header, int, error) {
+	data := make([]byte, maxMessageSize)
+	oob := make([]byte, unix.CmsgSpace(maxFds*4))
+	n, oobn, flags, _, err := conn.ReadMsgUnix(data, oob)
+	if err != nil {
+		if err == io.EOF {
+			return nil, -1, err
+		}
+		return nil, -1, errors.Wrap(err, "failed to receive the object")
+	}
+	if n == 0 && oobn == 0 {
+		return nil, -1, io.EOF
+	}
+	fds, err := parseRights(oob[:oobn])
+	if err != nil {
+		closeFds(fds)
+		return nil, -1, err
+	}
+	if flags&unix.MSG_CTRUNC != 0 {
+		closeFds(fds)
+		return nil, -1, errors.New("control message was truncated")
+	}
+	if len(fds) != 1 {
+		closeFds(fds)
+		return nil, -1, errors.Errorf("one descriptor was expected, %d were received", len(fds))
+	}
+	hdr := new(header)
+	if err = hdr.decode(data[:n]); err != nil {
+		closeFds(fds)
+		return nil, -1, err
+	}
+	unix.CloseOnExec(fds[0])
+	return hdr, fds[0], nil
+}
+
+// parseRights returns all the descriptors from the control messages.
+// if an error occurs, it returns the descriptors, which have been parsed.
+func parseRights(oob []byte) ([]int, error) {
+	msgs, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse control message")
+	}
+	var result []int
+	for i := range msgs {
+		if msgs[i].Header.Level != unix.SOL_SOCKET || msgs[i].Header.Type != unix.SCM_RIGHTS {
+			continue
+		}
+		fds, err := unix.ParseUnixRights(&msgs[i])
+		if err != nil {
+			return result, errors.Wrap(err, "failed to parse control message")
+		}
+		result = append(result, fds...)
+	}
+	return result, nil
+}
+
+func closeFds(fds []int) {
+	for _, fd := range fds {
+		unix.Close(fd)
+	}
+}
+
+// EventFd is a linux event notification object. It holds a 64-bit counter,
+// which is increased by writes and decreased by reads.
+type EventFd struct {
+	fd int
+}
+
+// NewEventFd creates a new eventfd.
+//	initval - initial value of the counter.
+//	flag - a combination of EFD_SEMAPHORE and EFD_NONBLOCK.
+func NewEventFd(initval uint, flag int) (*EventFd, error) {
+	if flag&^(EFD_SEMAPHORE|EFD_NONBLOCK) != 0 {
+		return nil, errors.Errorf("invalid eventfd flags %#x", flag)
+	}
+	fd, err := unix.Eventfd(initval, flag|unix.EFD_CLOEXEC)
+	if err != nil {
+		return nil, errors.Wrap(os.NewSyscallError("eventfd", err), "failed to create eventfd")
+	}
+	return &EventFd{fd: fd}, nil
+}
+
+// NewEventFdFromFd returns an eventfd for the given descriptor.
+// The eventfd takes the ownership of the descriptor.
+func NewEventFdFromFd(fd int) *EventFd {
+	return &EventFd{fd: fd}
+}
+
+// Fd returns the descriptor of the eventfd.
+func (ev *EventFd) Fd() int {
+	return ev.fd
+}
+
+// Read returns the value of the counter and resets it to 0.
+// If EFD_SEMAPHORE was set, it returns 1 and decrements the counter.
+// If the counter is 0, it blocks, or returns EAGAIN, if EFD_NONBLOCK was set.
+func (ev *EventFd) Read() (uint64, error) {
+	var value uint64
+	buf := (*[8]byte)(unsafe.Pointer(&value))[:]
+	err := common.UninterruptedSyscall(func() error {
+		_, err := unix.Read(ev.fd, buf)
+		return err
+	})
+	if err != nil {
+		return 0, errors.Wrap(os.NewSyscallError("read", err), "eventfd read failed")
+	}
+	return value, nil
+}
+
+// Write adds the value to the counter.
+func (ev *EventFd) Write(value uint64) error {
+	buf := (*[8]byte)(unsafe.Pointer(&value))[:]
+	err := common.UninterruptedSyscall(func() error {
+		_, err := unix.Write(ev.fd, buf)
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(os.NewSyscallError("write", err), "eventfd write failed")
+	}
+	return nil
+}
+
+// Close closes the eventfd.
+func (ev *EventFd) Close() error {
+	if ev.fd < 0 {
+		return nil
+	}
+	err := unix.Close(ev.fd)
+	ev.fd = -1
+	return err
+}
@@ -0,0 +1,171 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package fdpass
+
+import (
+	"io"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/mq"
+	"github.com/nxgtw/go-ipc/shm"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	testMqName = "fdpass-test-mq"
+)
+
+func unixConnPair(t *testing.T, typ int) (*net.UnixConn, *net.UnixConn) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, typ|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result [2]*net.UnixConn
+	for i, fd := range fds {
+		file := os.NewFile(uintptr(fd), "socketpair")
+		conn, err := net.FileConn(file)
+		file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		result[i] = conn.(*net.UnixConn)
+	}
+	return result[0], result[1]
+}
+
+func TestPassMemoryObject(t *testing.T) {
+	for _, typ := range []int{unix.SOCK_STREAM, unix.SOCK_SEQPACKET} {
+		testPassMemoryObject(t, typ)
+	}
+}
+
+func testPassMemoryObject(t *testing.T, typ int) {
+	a := assert.New(t)
+	c1, c2 := unixConnPair(t, typ)
+	defer c1.Close()
+	defer c2.Close()
+	obj, err := shm.NewAnonymousMemoryObject(4096)
+	if !a.NoError(err) {
+		return
+	}
+	defer obj.Destroy()
+	if !a.NoError(SendMemoryObject(c1, obj)) {
+		return
+	}
+	received, err := ReceiveMemoryObject(c2)
+	if !a.NoError(err) {
+		return
+	}
+	defer received.Destroy()
+	a.Equal(int64(4096), received.Size())
+	r1, err := mmf.NewMemoryRegion(obj, mmf.MEM_READWRITE, 0, 4096)
+	if !a.NoError(err) {
+		return
+	}
+	defer r1.Close()
+	r2, err := mmf.NewMemoryRegion(received, mmf.MEM_READ_ONLY, 0, 4096)
+	if !a.NoError(err) {
+		return
+	}
+	defer r2.Close()
+	copy(r1.Data(), []byte("fdpass"))
+	a.Equal([]byte("fdpass"), r2.Data()[:6])
+}
+
+func TestPassLinuxMessageQueue(t *testing.T) {
+	a := assert.New(t)
+	c1, c2 := unixConnPair(t, unix.SOCK_SEQPACKET)
+	defer c1.Close()
+	defer c2.Close()
+	if !a.NoError(mq.DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	queue, err := mq.CreateLinuxMessageQueue(testMqName, os.O_EXCL, 0666, 3, 64)
+	if !a.NoError(err) {
+		return
+	}
+	defer queue.Close()
+	if !a.NoError(SendLinuxMessageQueue(c1, queue)) {
+		return
+	}
+	received, err := ReceiveLinuxMessageQueue(c2, mq.O_NONBLOCK)
+	if !a.NoError(err) {
+		return
+	}
+	defer received.Destroy()
+	a.Equal(testMqName, received.Name())
+	a.Equal(3, received.Cap())
+	a.NoError(queue.Send([]byte{1, 2, 3}))
+	data := make([]byte, 64)
+	l, err := received.Receive(data)
+	a.NoError(err)
+	a.Equal([]byte{1, 2, 3}, data[:l])
+}
+
+func TestPassEventFd(t *testing.T) {
+	a := assert.New(t)
+	c1, c2 := unixConnPair(t, unix.SOCK_STREAM)
+	defer c1.Close()
+	defer c2.Close()
+	ev, err := NewEventFd(0, 0)
+	if !a.NoError(err) {
+		return
+	}
+	defer ev.Close()
+	if !a.NoError(SendEventFd(c1, ev)) {
+		return
+	}
+	obj, err := Receive(c2)
+	if !a.NoError(err) {
+		return
+	}
+	defer obj.Close()
+	received, ok := obj.(*EventFd)
+	if !a.True(ok) {
+		return
+	}
+	a.NoError(ev.Write(3))
+	a.NoError(ev.Write(4))
+	value, err := received.Read()
+	a.NoError(err)
+	a.Equal(uint64(7), value)
+	_, err = NewEventFd(0, unix.EFD_CLOEXEC)
+	a.Error(err)
+}
+
+func TestReceiveWrongType(t *testing.T) {
+	a := assert.New(t)
+	c1, c2 := unixConnPair(t, unix.SOCK_SEQPACKET)
+	defer c1.Close()
+	defer c2.Close()
+	ev, err := NewEventFd(0, EFD_NONBLOCK)
+	if !a.NoError(err) {
+		return
+	}
+	defer ev.Close()
+	if !a.NoError(SendEventFd(c1, ev)) {
+		return
+	}
+	_, err = ReceiveMemoryObject(c2)
+	a.Error(err)
+	c1.Close()
+	_, err = Receive(c2)
+	a.Equal(io.EOF, err)
+}
+
+func TestReceiveNoDescriptor(t *testing.T) {
+	a := assert.New(t)
+	c1, c2 := unixConnPair(t, unix.SOCK_SEQPACKET)
+	defer c1.Close()
+	defer c2.Close()
+	hdr := &header{typ: EventFdType}
+	_, err := c1.Write(hdr.encode())
+	a.NoError(err)
+	_, err = Receive(c2)
+	a.Error(err)
+}
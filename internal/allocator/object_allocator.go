@@ -90,7 +90,7 @@ func Alloc(memory []byte, object interface{}) error {
 	if size > len(memory) {
 		return fmt.Errorf("the object is too large for the buffer")
 	}
-	if err := checkType(value.Type(), 0); err != nil {
+	if err := checkType(value.Type(), 0, false); err != nil {
 		return err
 	}
 	copyObjectData(value, memory)
@@ -135,7 +135,7 @@ func AdvancePointer(p unsafe.Pointer, shift uintptr) unsafe.Pointer {
 // Slices of plain objects are allowed.
 func ObjectData(object interface{}) ([]byte, error) {
 	value := reflect.ValueOf(object)
-	if err := checkType(value.Type(), 0); err != nil {
+	if err := checkType(value.Type(), 0, false); err != nil {
 		return nil, err
 	}
 	var data []byte
@@ -170,35 +170,44 @@ func IsReferenceType(object interface{}) bool {
 // maps, strings, and so on.
 // slices or pointers can be at the top level only
 func CheckObjectReferences(object interface{}) error {
-	return checkType(reflect.ValueOf(object).Type(), 0)
+	return checkType(reflect.ValueOf(object).Type(), 0, false)
 }
 
-func checkType(t reflect.Type, depth int) error {
+// CheckValueType checks if values of the type can be placed into shared memory directly.
+// Unlike CheckObjectReferences, it rejects slices and pointers at the top level, and unsafe pointers.
+func CheckValueType(t reflect.Type) error {
+	return checkType(t, 1, true)
+}
+
+func checkType(t reflect.Type, depth int, strict bool) error {
 	kind := t.Kind()
 	if kind == reflect.Array {
-		return checkType(t.Elem(), depth+1)
+		return checkType(t.Elem(), depth+1, strict)
 	}
 	if kind == reflect.Slice {
 		if depth != 0 {
 			return fmt.Errorf("unexpected slice type")
 		}
-		return checkType(t.Elem(), depth+1)
+		return checkType(t.Elem(), depth+1, strict)
 	}
 	if kind == reflect.Ptr {
 		if depth != 0 {
 			return fmt.Errorf("unexpected pointer type")
 		}
-		return checkType(t.Elem(), depth+1)
+		return checkType(t.Elem(), depth+1, strict)
 	}
 	if kind == reflect.Struct {
 		for i := 0; i < t.NumField(); i++ {
 			field := t.Field(i)
-			if err := checkType(field.Type, depth+1); err != nil {
+			if err := checkType(field.Type, depth+1, strict); err != nil {
 				return fmt.Errorf("field %s: %v", field.Name, err)
 			}
 		}
 		return nil
 	}
+	if strict && kind == reflect.UnsafePointer {
+		return fmt.Errorf("unexpected unsafe pointer type")
+	}
 	return checkNumericType(kind)
 }
 
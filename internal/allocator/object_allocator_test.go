@@ -3,6 +3,7 @@
 package allocator
 
 import (
+	"reflect"
 	"sync"
 	"testing"
 	"unsafe"
@@ -50,6 +51,25 @@ func TestCheckObjectType(t *testing.T) {
 	assert.Error(t, CheckObjectReferences(slsl))
 }
 
+func TestCheckValueType(t *testing.T) {
+	type validStruct struct {
+		a   int32
+		arr [4]uint64
+	}
+	type invalidStruct struct {
+		a int
+		p unsafe.Pointer
+	}
+	assert.NoError(t, CheckValueType(reflect.TypeOf(int64(0))))
+	assert.NoError(t, CheckValueType(reflect.TypeOf(validStruct{})))
+	assert.NoError(t, CheckValueType(reflect.TypeOf([3]validStruct{})))
+	assert.Error(t, CheckValueType(reflect.TypeOf(&validStruct{})))
+	assert.Error(t, CheckValueType(reflect.TypeOf([]int{})))
+	assert.Error(t, CheckValueType(reflect.TypeOf(invalidStruct{})))
+	assert.Error(t, CheckValueType(reflect.TypeOf("")))
+	assert.NoError(t, CheckObjectReferences(invalidStruct{}))
+}
+
 func TestAllocInt(t *testing.T) {
 	var i = 0x01027FFF
 	data := make([]byte, unsafe.Sizeof(i))
@@ -0,0 +1,218 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// Package attach implements a shared table of processes, which use a named object.
+// It allows to find out, when the last user of an object closes it.
+package attach
+
+import (
+	"os"
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/common"
+	"github.com/nxgtw/go-ipc/internal/helper"
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// table is a shared memory object with the following layout:
+	//	guard (pid of the owner) | destroyed flag | padding | slots
+	headerSize = 64
+	slotCount  = 256
+	tableSize  = headerSize + slotCount*int(unsafe.Sizeof(slot{}))
+)
+
+type header struct {
+	guard     int32
+	destroyed int32
+}
+
+// slot holds the number of attachments of a process.
+type slot struct {
+	pid   int32
+	count int32
+}
+
+// Table is a list of processes, which use a named object.
+// Processes, which exited without detaching, are removed from the table, when it is accessed.
+type Table struct {
+	name   string
+	region *mmf.MemoryRegion
+	hdr    *header
+	slots  *[slotCount]slot
+	pid    int32
+}
+
+// Flags returns true, if the flag has any of attach flags set.
+func Flags(flag int) bool {
+	return flag&(common.O_ATTACH|common.O_AUTODESTROY) != 0
+}
+
+// StripFlags removes attach flags from the flag.
+func StripFlags(flag int) int {
+	return flag &^ (common.O_ATTACH | common.O_AUTODESTROY)
+}
+
+// Attach opens a table with the given name, creating it if needed,
+// and registers the current process in it.
+// The attach must be done before the object is opened, so that it is not destroyed in meanwhile.
+func Attach(name string, perm os.FileMode) (*Table, error) {
+	for {
+		region, _, err := helper.CreateWritableRegion(name, os.O_CREATE, perm, tableSize)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open attach table")
+		}
+		data := allocator.ByteSliceData(region.Data())
+		t := &Table{
+			name:   name,
+			region: region,
+			hdr:    (*header)(data),
+			slots:  (*[slotCount]slot)(allocator.AdvancePointer(data, headerSize)),
+			pid:    int32(os.Getpid()),
+		}
+		tookOver := t.lock()
+		if atomic.LoadInt32(&t.hdr.destroyed) != 0 {
+			// the last user has just destroyed the object. try again with a new table.
+			// if it died holding the guard, it has not removed the table, and it must be done here,
+			// otherwise the table would be reopened forever.
+			if tookOver {
+				err = Destroy(name)
+			}
+			t.unlock()
+			region.Close()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		err = t.add(1)
+		t.unlock()
+		if err != nil {
+			region.Close()
+			return nil, err
+		}
+		return t, nil
+	}
+}
+
+// Count returns the number of attachments of alive processes.
+func (t *Table) Count() int {
+	t.lock()
+	defer t.unlock()
+	return t.reconcile()
+}
+
+// Detach unregisters the current process. If there are no more attached processes,
+// it calls destroy, if it is not nil, and removes the table.
+// It returns true, if destroy was called.
+func (t *Table) Detach(destroy func() error) (bool, error) {
+	if t.region == nil {
+		return false, nil
+	}
+	defer func() {
+		t.region.Close()
+		t.region = nil
+	}()
+	t.lock()
+	defer t.unlock()
+	t.add(-1)
+	if t.reconcile() > 0 {
+		return false, nil
+	}
+	// other processes may wait for the guard to attach. the flag makes them open a new table.
+	atomic.StoreInt32(&t.hdr.destroyed, 1)
+	var err error
+	if destroy != nil {
+		err = destroy()
+	}
+	if errTable := shm.DestroyMemoryObject(t.name); err == nil && errTable != nil {
+		err = errors.Wrap(errTable, "failed to destroy attach table")
+	}
+	return destroy != nil, err
+}
+
+// Destroy removes a table with the given name.
+func Destroy(name string) error {
+	if err := shm.DestroyMemoryObject(name); err != nil {
+		return errors.Wrap(err, "failed to destroy attach table")
+	}
+	return nil
+}
+
+// add changes the count of the current process. it must be called with the guard locked.
+func (t *Table) add(delta int32) error {
+	var free *slot
+	for i := range t.slots {
+		s := &t.slots[i]
+		if s.pid == t.pid {
+			if s.count += delta; s.count <= 0 {
+				*s = slot{}
+			}
+			return nil
+		}
+		if s.pid == 0 && free == nil {
+			free = s
+		}
+	}
+	if delta < 0 {
+		return nil
+	}
+	if free == nil {
+		t.reconcile()
+		for i := range t.slots {
+			if t.slots[i].pid == 0 {
+				free = &t.slots[i]
+				break
+			}
+		}
+	}
+	if free == nil {
+		return errors.New("too many processes are attached to the object")
+	}
+	*free = slot{pid: t.pid, count: delta}
+	return nil
+}
+
+// reconcile frees slots of dead processes and returns the number of attachments.
+// it must be called with the guard locked.
+func (t *Table) reconcile() int {
+	var result int
+	for i := range t.slots {
+		s := &t.slots[i]
+		if s.pid == 0 {
+			continue
+		}
+		if s.pid != t.pid && !common.ProcessAlive(int(s.pid)) {
+			*s = slot{}
+			continue
+		}
+		result += int(s.count)
+	}
+	return result
+}
+
+// lock acquires the guard. if its owner has died, the guard is taken over, and true is returned.
+func (t *Table) lock() bool {
+	for {
+		owner := atomic.LoadInt32(&t.hdr.guard)
+		if owner == 0 {
+			if atomic.CompareAndSwapInt32(&t.hdr.guard, 0, t.pid) {
+				return false
+			}
+		} else if owner != t.pid && !common.ProcessAlive(int(owner)) {
+			if atomic.CompareAndSwapInt32(&t.hdr.guard, owner, t.pid) {
+				return true
+			}
+		}
+		runtime.Gosched()
+	}
+}
+
+func (t *Table) unlock() {
+	atomic.StoreInt32(&t.hdr.guard, 0)
+}
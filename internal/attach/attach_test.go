@@ -0,0 +1,162 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package attach
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/nxgtw/go-ipc/internal/common"
+	"github.com/nxgtw/go-ipc/shm"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testTableName = "go-ipc.attach-test"
+)
+
+func deadPid(t *testing.T) int32 {
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return int32(cmd.Process.Pid)
+}
+
+func TestAttachDetach(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(Destroy(testTableName)) {
+		return
+	}
+	t1, err := Attach(testTableName, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer Destroy(testTableName)
+	t2, err := Attach(testTableName, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(2, t1.Count())
+	var destroyed bool
+	destroy := func() error {
+		destroyed = true
+		return nil
+	}
+	ok, err := t1.Detach(destroy)
+	a.NoError(err)
+	a.False(ok)
+	a.False(destroyed)
+	a.Equal(1, t2.Count())
+	ok, err = t2.Detach(destroy)
+	a.NoError(err)
+	a.True(ok)
+	a.True(destroyed)
+	_, err = shm.NewMemoryObject(testTableName, os.O_RDONLY, 0666)
+	a.Error(err)
+}
+
+func TestAttachDeadProcess(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(Destroy(testTableName)) {
+		return
+	}
+	table, err := Attach(testTableName, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer Destroy(testTableName)
+	pid := deadPid(t)
+	table.slots[1] = slot{pid: pid, count: 3}
+	// the process has exited, while holding the guard.
+	table.hdr.guard = pid
+	a.Equal(1, table.Count())
+	a.Equal(slot{}, table.slots[1])
+	var destroyed bool
+	_, err = table.Detach(func() error {
+		destroyed = true
+		return nil
+	})
+	a.NoError(err)
+	a.True(destroyed)
+}
+
+func TestAttachInterruptedDestroy(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(Destroy(testTableName)) {
+		return
+	}
+	table, err := Attach(testTableName, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer Destroy(testTableName)
+	// the last user has died, while destroying the object, and the table was left with the flag set.
+	table.slots[0] = slot{}
+	table.hdr.destroyed = 1
+	table.hdr.guard = deadPid(t)
+	table.region.Close()
+	done := make(chan *Table)
+	go func() {
+		table, err := Attach(testTableName, 0666)
+		a.NoError(err)
+		done <- table
+	}()
+	select {
+	case table = <-done:
+		if a.NotNil(table) {
+			a.Equal(int32(0), table.hdr.destroyed)
+			a.Equal(1, table.Count())
+			table.Detach(nil)
+		}
+	case <-time.After(time.Second * 5):
+		a.Fail("attach to a stale table did not return")
+	}
+}
+
+func TestAttachment(t *testing.T) {
+	a := assert.New(t)
+	var nilAttachment *Attachment
+	_, err := nilAttachment.Count()
+	a.Equal(ErrNotAttached, err)
+	a.NoError(nilAttachment.Close(nil))
+	if !a.NoError(Destroy(testTableName)) {
+		return
+	}
+	att, err := Open(testTableName, 0, 0666)
+	a.NoError(err)
+	a.Nil(att)
+	att1, err := Open(testTableName, common.O_ATTACH, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer Destroy(testTableName)
+	att2, err := Open(testTableName, common.O_AUTODESTROY, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	count, err := att2.Count()
+	a.NoError(err)
+	a.Equal(2, count)
+	var destroyed int
+	destroy := func() error {
+		destroyed++
+		return nil
+	}
+	a.NoError(att2.Close(destroy))
+	a.Equal(0, destroyed)
+	att3, err := Open(testTableName, common.O_AUTODESTROY, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	// the object is not destroyed by a process, which opened it without O_AUTODESTROY.
+	a.NoError(att1.Close(destroy))
+	a.Equal(0, destroyed)
+	a.NoError(att3.Close(destroy))
+	a.Equal(1, destroyed)
+	_, err = shm.NewMemoryObject(testTableName, os.O_RDONLY, 0666)
+	a.Error(err)
+}
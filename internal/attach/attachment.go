@@ -0,0 +1,67 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package attach
+
+import (
+	"os"
+
+	"github.com/nxgtw/go-ipc/internal/common"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrNotAttached is returned by Count, if the object was opened without attach flags.
+	ErrNotAttached = errors.New("the object was opened without O_ATTACH")
+)
+
+// Attachment ties an object to its attach table, so that the object is destroyed with O_AUTODESTROY,
+// when the last attached process closes it. A nil attachment is valid and does nothing.
+type Attachment struct {
+	table       *Table
+	autoDestroy bool
+}
+
+// Open registers the process in the table with the given name, if the flag has any of attach flags set.
+// Otherwise, it returns nil. It must be called before the object is opened, so that it is not destroyed in meanwhile.
+func Open(name string, flag int, perm os.FileMode) (*Attachment, error) {
+	if !Flags(flag) {
+		return nil, nil
+	}
+	table, err := Attach(name, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &Attachment{table: table, autoDestroy: flag&common.O_AUTODESTROY != 0}, nil
+}
+
+// Count returns the number of attachments of alive processes.
+func (a *Attachment) Count() (int, error) {
+	if a == nil {
+		return 0, ErrNotAttached
+	}
+	return a.table.Count(), nil
+}
+
+// Close unregisters the process. If it was the last attached process, and the object
+// was opened with O_AUTODESTROY, destroy is called to remove the object.
+// destroy must not remove the table, as Close removes it itself.
+func (a *Attachment) Close(destroy func() error) error {
+	if a == nil {
+		return nil
+	}
+	if !a.autoDestroy {
+		destroy = nil
+	}
+	if _, err := a.table.Detach(destroy); err != nil {
+		return errors.Wrap(err, "failed to detach from the object")
+	}
+	return nil
+}
+
+// Cancel unregisters the process, if the object could not be opened.
+func (a *Attachment) Cancel() {
+	if a != nil {
+		a.table.Detach(nil)
+	}
+}
@@ -13,6 +13,15 @@ const (
 	// O_NONBLOCK flag tell some functions not to block.
 	// Its value does not interfere with O_* constants from 'os' package.
 	O_NONBLOCK = syscall.O_NONBLOCK
+	// O_ATTACH flag tells an object to register the process in its attach counter.
+	// Its value does not interfere with O_* constants from 'os' package.
+	O_ATTACH = 0x10000000
+	// O_AUTODESTROY flag tells an object to destroy itself, when the last attached process closes it.
+	// It implies O_ATTACH.
+	O_AUTODESTROY = 0x20000000
+	// O_HUGETLB flag tells a shared memory object to use huge pages.
+	// Its value does not interfere with O_* constants from 'os' package.
+	O_HUGETLB = 0x40000000
 )
 
 // Destroyer is an object which can be permanently removed.
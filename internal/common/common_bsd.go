@@ -0,0 +1,11 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build darwin freebsd
+
+package common
+
+// HugePageSize returns the size of the huge page, if the file is backed by huge pages, or 0.
+// Huge pages are not supported on this platform, so it always returns 0.
+func HugePageSize(fd uintptr) int64 {
+	return 0
+}
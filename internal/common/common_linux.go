@@ -0,0 +1,21 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package common
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// HugetlbfsMagic is the type of hugetlbfs filesystem, as returned by statfs.
+	HugetlbfsMagic = 0x958458f6
+)
+
+// HugePageSize returns the size of the huge page, if the file is backed by huge pages, or 0.
+func HugePageSize(fd uintptr) int64 {
+	var statfs unix.Statfs_t
+	if err := unix.Fstatfs(int(fd), &statfs); err != nil || int64(statfs.Type) != HugetlbfsMagic {
+		return 0
+	}
+	return int64(statfs.Bsize)
+}
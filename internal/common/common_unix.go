@@ -50,9 +50,14 @@ func KeyForName(name string) (Key, error) {
 	return k, nil
 }
 
-// TmpFilename returns a full path for a temporary file with the given name.
+// FileKey returns a key for an existing file, which is the same, as KeyForName generates for it.
+func FileKey(path string) (Key, error) {
+	return ftok(path)
+}
+
+// TmpFilename returns a full path for a temporary file with the given name in current namespace.
 func TmpFilename(name string) string {
-	return os.TempDir() + "/" + name
+	return os.TempDir() + "/" + Namespaced(name)
 }
 
 // AbsTimeoutToTimeSpec converts given timeout value to absulute value of unix.Timespec.
@@ -133,6 +138,12 @@ func IsTimeoutErr(err error) bool {
 	return false
 }
 
+// ProcessAlive returns true, if a process with the given pid exists.
+func ProcessAlive(pid int) bool {
+	err := unix.Kill(pid, 0)
+	return err == nil || err == unix.EPERM
+}
+
 // NewTimeoutError returns new syscall error with EAGAIN code.
 func NewTimeoutError(op string) error {
 	return os.NewSyscallError(op, unix.EAGAIN)
@@ -5,10 +5,15 @@ package common
 import (
 	"os"
 	"syscall"
+
+	"golang.org/x/sys/windows"
 )
 
 const (
 	cERROR_TIMEOUT = syscall.Errno(1460)
+
+	cPROCESS_QUERY_LIMITED_INFORMATION = 0x1000
+	cSTILL_ACTIVE                      = 259
 )
 
 // IsTimeoutErr returns true, if the given error is a temporary syscall error.
@@ -25,3 +30,18 @@ func IsTimeoutErr(err error) bool {
 func NewTimeoutError(op string) error {
 	return os.NewSyscallError(op, cERROR_TIMEOUT)
 }
+
+// ProcessAlive returns true, if a process with the given pid exists.
+func ProcessAlive(pid int) bool {
+	h, err := windows.OpenProcess(cPROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		// access to the process may be denied, but it exists.
+		return err == windows.ERROR_ACCESS_DENIED
+	}
+	defer windows.CloseHandle(h)
+	var code uint32
+	if err = windows.GetExitCodeProcess(h, &code); err != nil {
+		return true
+	}
+	return code == cSTILL_ACTIVE
+}
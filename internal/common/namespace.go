@@ -0,0 +1,80 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package common
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	// NamespaceEnv is an environment variable, which sets the initial namespace.
+	NamespaceEnv = "GO_IPC_NAMESPACE"
+	// namespaceSeparator separates the namespace from the name. it can't be used in namespaces,
+	// so that the namespace can be found unambiguously.
+	namespaceSeparator = "."
+)
+
+var (
+	namespaceMu sync.RWMutex
+	namespace   string
+)
+
+func init() {
+	if ns := os.Getenv(NamespaceEnv); CheckNamespace(ns) == nil {
+		namespace = ns
+	}
+}
+
+// CheckNamespace returns an error, if the namespace contains characters other than
+// latin letters, digits, '-', and '_'. An empty namespace is valid.
+func CheckNamespace(ns string) error {
+	for _, c := range ns {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return fmt.Errorf("invalid namespace %q", ns)
+		}
+	}
+	return nil
+}
+
+// SetNamespace sets the namespace, which is added to the names of all objects.
+func SetNamespace(ns string) error {
+	if err := CheckNamespace(ns); err != nil {
+		return err
+	}
+	namespaceMu.Lock()
+	namespace = ns
+	namespaceMu.Unlock()
+	return nil
+}
+
+// Namespace returns current namespace.
+func Namespace() string {
+	namespaceMu.RLock()
+	defer namespaceMu.RUnlock()
+	return namespace
+}
+
+// Namespaced returns the name, which an object with the given name has in the os.
+func Namespaced(name string) string {
+	if ns := Namespace(); len(ns) > 0 {
+		return ns + namespaceSeparator + name
+	}
+	return name
+}
+
+// StripNamespace returns the name of an object without current namespace.
+// It returns false, if the object does not belong to the namespace.
+func StripNamespace(name string) (string, bool) {
+	ns := Namespace()
+	if len(ns) == 0 {
+		return name, true
+	}
+	prefix := ns + namespaceSeparator
+	if !strings.HasPrefix(name, prefix) || len(name) == len(prefix) {
+		return "", false
+	}
+	return name[len(prefix):], true
+}
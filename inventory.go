@@ -0,0 +1,396 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package ipc
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nxgtw/go-ipc/internal/common"
+
+	"github.com/pkg/errors"
+)
+
+// ResourceKind is a kind of an OS resource, which backs a go-ipc object.
+type ResourceKind int
+
+const (
+	// SharedMemoryResource is a shared memory object.
+	SharedMemoryResource ResourceKind = iota
+	// SysVSemaphoreResource is a SysV semaphore set.
+	SysVSemaphoreResource
+	// SysVMessageQueueResource is a SysV message queue.
+	SysVMessageQueueResource
+	// KeyFileResource is a temporary file, which is used to generate a key for a SysV object.
+	KeyFileResource
+)
+
+func (k ResourceKind) String() string {
+	switch k {
+	case SharedMemoryResource:
+		return "shm"
+	case SysVSemaphoreResource:
+		return "sysv-sem"
+	case SysVMessageQueueResource:
+		return "sysv-msg"
+	case KeyFileResource:
+		return "keyfile"
+	default:
+		return "unknown"
+	}
+}
+
+// Types of go-ipc objects, which can be found by ListObjects.
+const (
+	MemoryObjectType     = "MemoryObject"
+	FutexMutexType       = "FutexMutex"
+	SemaMutexType        = "SemaMutex"
+	SpinMutexType        = "SpinMutex"
+	EventMutexType       = "EventMutex"
+	PIMutexType          = "PIMutex"
+	RecursiveMutexType   = "RecursiveMutex"
+	RWMutexType          = "RWMutex"
+	SemaphoreType        = "Semaphore"
+	EventType            = "Event"
+	CondType             = "Cond"
+	FastMqType           = "FastMq"
+	SysVMessageQueueType = "SysVMessageQueue"
+	DebugRegistryType    = "DebugRegistry"
+)
+
+// Resource is an OS resource, which is a part of a go-ipc object.
+type Resource struct {
+	Kind ResourceKind
+	// Name is the name of a shared memory object, or of a key file, without current namespace.
+	// For SysV objects it is the name of their key file.
+	Name string
+	// Path is the path of a shared memory object or of a key file. It is empty for SysV objects.
+	Path string
+	// ID is the id of a SysV object, or -1.
+	ID int
+	// Key is the key of a SysV object or of a key file.
+	Key uint64
+	// Size is the size of a shared memory object, or the number of bytes in a SysV message queue.
+	Size int64
+	UID  int
+	GID  int
+	Mode os.FileMode
+	// ModTime is the time of the last change of the resource.
+	ModTime time.Time
+	// Pids are the ids of processes, which have the resource opened or mapped.
+	// It is nil for SysV objects, as the kernel does not report their users.
+	Pids []int
+}
+
+// Attached returns the number of processes, which have the resource opened or mapped,
+// or -1, if it is unknown.
+func (r *Resource) Attached() int {
+	if r.Kind == SysVSemaphoreResource || r.Kind == SysVMessageQueueResource {
+		return -1
+	}
+	return len(r.Pids)
+}
+
+// Object is a go-ipc object, which consists of one or several OS resources.
+type Object struct {
+	// Name is the name of the object, as it was passed to its constructor.
+	Name string
+	// Type is one of the object type constants, like FutexMutexType.
+	Type      string
+	Resources []Resource
+}
+
+// Age returns time passed since the last change of any of the object's resources.
+func (o *Object) Age() time.Duration {
+	var last time.Time
+	for _, r := range o.Resources {
+		if r.ModTime.After(last) {
+			last = r.ModTime
+		}
+	}
+	return time.Since(last)
+}
+
+// Attached returns the number of processes, which use the object,
+// or -1, if it can't be determined for any of its resources.
+func (o *Object) Attached() int {
+	if len(o.Resources) == 0 {
+		return -1
+	}
+	var result int
+	for i := range o.Resources {
+		attached := o.Resources[i].Attached()
+		if attached < 0 {
+			return -1
+		}
+		if attached > result {
+			result = attached
+		}
+	}
+	return result
+}
+
+// Owners returns the ids of processes, which use the object.
+func (o *Object) Owners() []int {
+	set := make(map[int]struct{})
+	for _, r := range o.Resources {
+		for _, pid := range r.Pids {
+			set[pid] = struct{}{}
+		}
+	}
+	result := make([]int, 0, len(set))
+	for pid := range set {
+		result = append(result, pid)
+	}
+	sort.Ints(result)
+	return result
+}
+
+// Orphan returns true, if no process uses the object.
+// Objects with unknown attach count, for example, SysV semaphores, are never orphans.
+func (o *Object) Orphan() bool {
+	return o.Attached() == 0
+}
+
+// Destroy removes all resources of the object.
+func (o *Object) Destroy() error {
+	var result error
+	// key files must be removed last, as they are needed to find the SysV objects.
+	for _, kind := range [...]ResourceKind{SysVSemaphoreResource, SysVMessageQueueResource, SharedMemoryResource, KeyFileResource} {
+		for i := range o.Resources {
+			if r := &o.Resources[i]; r.Kind == kind {
+				if err := destroyResource(r); err != nil && result == nil {
+					result = errors.Wrapf(err, "failed to destroy %s %q", r.Kind, r.Name)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// Inventory is a list of go-ipc objects, found in the system.
+type Inventory struct {
+	Objects []Object
+	// Uninspected is the number of processes, which could not be inspected due to lack of permissions.
+	// If it is not zero, the objects may be used by more processes, than reported.
+	Uninspected int
+}
+
+// ListObjects enumerates all go-ipc objects in the system. It recognizes objects by the names
+// of their shared memory objects and key files, so an object, created by shm.NewMemoryObject
+// directly, is reported as MemoryObjectType, even if it was not created by go-ipc.
+// SysV objects are found only if their key files exist.
+// Currently it is supported on linux only.
+// If a namespace is set with shm.SetNamespace, only the objects from it are reported,
+// and their names are given without the namespace.
+func ListObjects() (*Inventory, error) {
+	resources, uninspected, err := listResources()
+	if err != nil {
+		return nil, err
+	}
+	return &Inventory{Objects: groupResources(inNamespace(resources)), Uninspected: uninspected}, nil
+}
+
+// inNamespace returns the resources, which belong to current namespace, with the namespace removed from their names.
+func inNamespace(resources []Resource) []Resource {
+	result := resources[:0]
+	for _, r := range resources {
+		if name, ok := common.StripNamespace(r.Name); ok {
+			r.Name = name
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// DestroyOrphans destroys go-ipc objects, which are not used by any process,
+// and were not changed during minAge. minAge protects objects, which are being created right now.
+// Objects of MemoryObjectType are never destroyed, as they may belong to other applications.
+// It refuses to work, if some processes could not be inspected. It returns destroyed objects.
+func DestroyOrphans(minAge time.Duration) ([]Object, error) {
+	inv, err := ListObjects()
+	if err != nil {
+		return nil, err
+	}
+	if inv.Uninspected > 0 {
+		return nil, errors.Errorf("%d processes could not be inspected", inv.Uninspected)
+	}
+	var destroyed []Object
+	for _, o := range inv.Objects {
+		if o.Type == MemoryObjectType || !o.Orphan() || o.Age() < minAge {
+			continue
+		}
+		if err := o.Destroy(); err != nil {
+			return destroyed, err
+		}
+		destroyed = append(destroyed, o)
+	}
+	return destroyed, nil
+}
+
+var (
+	// shmSuffixes maps suffixes of shared memory objects to the type of their owners.
+	// see mutexSharedStateName, statsStateName, attachTableName, eventName, etc. in sync package.
+	shmSuffixes = map[string]string{
+		".stcond": CondType,
+		".stsem":  SemaphoreType,
+		".strw":   RWMutexType,
+		".stf":    FutexMutexType,
+		".srec":   RecursiveMutexType,
+		".srw":    RWMutexType,
+		".spi":    PIMutexType,
+		".sema":   SemaphoreType,
+		".sf":     FutexMutexType,
+		".ss":     SemaMutexType,
+		".se":     EventMutexType,
+		".ev":     EventType,
+		".st":     CondType,
+		".atf":    FutexMutexType,
+		".atspin": SpinMutexType,
+		".atrw":   RWMutexType,
+		".atmq":   FastMqType,
+	}
+	// keyFileSuffixes maps suffixes of key files to the type of their owners.
+	// key files without a known suffix belong to semaphores or message queues.
+	keyFileSuffixes = map[string]string{
+		".rs":   RWMutexType,
+		".ws":   RWMutexType,
+		".us":   RWMutexType,
+		".gs":   RWMutexType,
+		".recs": RecursiveMutexType,
+	}
+)
+
+const (
+	spinMutexPrefix   = "go-ipc.spin."
+	debugRegistryName = "go-ipc.debug.registry"
+	fastMqLockerName  = ".m"
+)
+
+// parseResourceName returns the name and the type of the object, which owns the resource.
+func parseResourceName(r *Resource) (string, string) {
+	switch r.Kind {
+	case SharedMemoryResource:
+		if r.Name == debugRegistryName {
+			return r.Name, DebugRegistryType
+		}
+		if strings.HasPrefix(r.Name, spinMutexPrefix) {
+			return strings.TrimPrefix(r.Name, spinMutexPrefix), SpinMutexType
+		}
+		if name, typ, ok := matchSuffix(r.Name, shmSuffixes); ok {
+			return name, typ
+		}
+		return r.Name, MemoryObjectType
+	case SysVMessageQueueResource:
+		return r.Name, SysVMessageQueueType
+	default:
+		if name, typ, ok := matchSuffix(r.Name, keyFileSuffixes); ok {
+			return name, typ
+		}
+		return r.Name, SemaphoreType
+	}
+}
+
+// matchSuffix finds the longest suffix of the name in the table.
+func matchSuffix(name string, suffixes map[string]string) (string, string, bool) {
+	var best string
+	for suffix := range suffixes {
+		if len(suffix) > len(best) && len(name) > len(suffix) && strings.HasSuffix(name, suffix) {
+			best = suffix
+		}
+	}
+	if len(best) == 0 {
+		return "", "", false
+	}
+	return strings.TrimSuffix(name, best), suffixes[best], true
+}
+
+// groupResources builds objects from their resources.
+func groupResources(resources []Resource) []Object {
+	objects := make(map[string]*Object)
+	find := func(name string, types ...string) *Object {
+		for _, typ := range types {
+			if o, ok := objects[name+"\x00"+typ]; ok {
+				return o
+			}
+		}
+		return nil
+	}
+	merge := func(dst, src *Object) {
+		dst.Resources = append(dst.Resources, src.Resources...)
+		delete(objects, src.Name+"\x00"+src.Type)
+	}
+	for _, r := range resources {
+		name, typ := parseResourceName(&r)
+		key := name + "\x00" + typ
+		o, ok := objects[key]
+		if !ok {
+			o = &Object{Name: name, Type: typ}
+			objects[key] = o
+		}
+		o.Resources = append(o.Resources, r)
+	}
+	// objects with the same name.
+	for _, o := range sortedObjects(objects) {
+		if o.Type != SemaphoreType {
+			continue
+		}
+		if m := find(o.Name, SemaMutexType); m != nil {
+			// semaphore mutex uses a semaphore with the same name.
+			merge(m, o)
+		} else if q := find(o.Name, SysVMessageQueueType); q != nil && len(o.Resources) == 1 {
+			// a key file of a message queue.
+			merge(q, o)
+		}
+	}
+	// objects, which consist of other objects.
+	mutexTypes := []string{FutexMutexType, SemaMutexType, EventMutexType, SpinMutexType}
+	for _, o := range sortedObjects(objects) {
+		switch o.Type {
+		case CondType:
+			// fast mq consists of its state, a mutex, and two condvars.
+			var mqName string
+			if strings.HasSuffix(o.Name, ".cvs") || strings.HasSuffix(o.Name, ".cvr") {
+				mqName = o.Name[:len(o.Name)-4]
+			} else if find(o.Name+".cvs", CondType) != nil || find(o.Name+".cvr", CondType) != nil {
+				mqName = o.Name
+			}
+			if len(mqName) == 0 {
+				// waitlist-based cond uses a mutex for its list of waiters.
+				if m := find(o.Name+fastMqLockerName, mutexTypes...); m != nil {
+					merge(o, m)
+				}
+				continue
+			}
+			mq := find(mqName, FastMqType)
+			if mq == nil {
+				mq = &Object{Name: mqName, Type: FastMqType}
+				objects[mqName+"\x00"+FastMqType] = mq
+			}
+			merge(mq, o)
+			if m := find(mqName+fastMqLockerName, mutexTypes...); m != nil {
+				merge(mq, m)
+			}
+		}
+	}
+	result := make([]Object, 0, len(objects))
+	for _, o := range sortedObjects(objects) {
+		result = append(result, *o)
+	}
+	return result
+}
+
+func sortedObjects(objects map[string]*Object) []*Object {
+	keys := make([]string, 0, len(objects))
+	for key := range objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]*Object, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, objects[key])
+	}
+	return result
+}
@@ -0,0 +1,259 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package ipc
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nxgtw/go-ipc/internal/common"
+	"github.com/nxgtw/go-ipc/shm"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	procSysVSem = "/proc/sysvipc/sem"
+	procSysVMsg = "/proc/sysvipc/msg"
+)
+
+func listResources() ([]Resource, int, error) {
+	dir, err := shm.Directory()
+	if err != nil {
+		return nil, 0, err
+	}
+	shmObjects, err := listFiles(dir, SharedMemoryResource)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to list shared memory objects")
+	}
+	sysv, err := listSysVObjects()
+	if err != nil {
+		return nil, 0, err
+	}
+	keyFiles, err := listFiles(os.TempDir(), KeyFileResource)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to list key files")
+	}
+	resources := shmObjects
+	// key files are empty files in the temporary directory. as there can be lots of
+	// such files, only those, which have a SysV object, or a known suffix, are reported.
+	sysvNames := make(map[uint64]string)
+	for _, kf := range keyFiles {
+		_, known := keyFileSuffixes[filepath.Ext(kf.Name)]
+		for i := range sysv {
+			if sysv[i].Key == kf.Key {
+				known = true
+				sysvNames[kf.Key] = kf.Name
+			}
+		}
+		if known {
+			resources = append(resources, kf)
+		}
+	}
+	for _, r := range sysv {
+		if name, ok := sysvNames[r.Key]; ok {
+			r.Name = name
+			resources = append(resources, r)
+		}
+	}
+	uninspected, err := findUsers(resources)
+	if err != nil {
+		return nil, 0, err
+	}
+	return resources, uninspected, nil
+}
+
+// listFiles returns all regular files in the directory. for key files it returns empty files only.
+func listFiles(dir string, kind ResourceKind) ([]Resource, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var result []Resource
+	for _, info := range infos {
+		if !info.Mode().IsRegular() || (kind == KeyFileResource && info.Size() != 0) {
+			continue
+		}
+		r := Resource{
+			Kind:    kind,
+			Name:    info.Name(),
+			Path:    filepath.Join(dir, info.Name()),
+			ID:      -1,
+			Size:    info.Size(),
+			Mode:    info.Mode().Perm(),
+			ModTime: info.ModTime(),
+		}
+		if st, ok := info.Sys().(*syscall.Stat_t); ok {
+			r.UID, r.GID = int(st.Uid), int(st.Gid)
+		}
+		if kind == KeyFileResource {
+			k, err := common.FileKey(r.Path)
+			if err != nil {
+				continue
+			}
+			r.Key = uint64(k)
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+func listSysVObjects() ([]Resource, error) {
+	sems, err := parseSysVTable(procSysVSem, SysVSemaphoreResource)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := parseSysVTable(procSysVMsg, SysVMessageQueueResource)
+	if err != nil {
+		return nil, err
+	}
+	return append(sems, msgs...), nil
+}
+
+// parseSysVTable parses a table from /proc/sysvipc. columns are located by the header.
+func parseSysVTable(path string, kind ResourceKind) ([]Resource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to open %s", path)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		return nil, scanner.Err()
+	}
+	columns := make(map[string]int)
+	for i, name := range strings.Fields(scanner.Text()) {
+		columns[name] = i
+	}
+	var result []Resource
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		value := func(name string) int64 {
+			idx, ok := columns[name]
+			if !ok || idx >= len(fields) {
+				return 0
+			}
+			v, _ := strconv.ParseInt(fields[idx], 10, 64)
+			return v
+		}
+		r := Resource{
+			Kind: kind,
+			ID:   int(value("semid") + value("msqid")),
+			Key:  uint64(uint32(value("key"))),
+			Size: value("cbytes"),
+			UID:  int(value("uid")),
+			GID:  int(value("gid")),
+		}
+		perms, _ := strconv.ParseUint(fields[columns["perms"]], 8, 32)
+		r.Mode = os.FileMode(perms).Perm()
+		for _, column := range []string{"otime", "stime", "rtime", "ctime"} {
+			if t := time.Unix(value(column), 0); t.After(r.ModTime) {
+				r.ModTime = t
+			}
+		}
+		result = append(result, r)
+	}
+	return result, scanner.Err()
+}
+
+// findUsers finds processes, which have files of the resources mapped or opened.
+// it returns the number of processes, which could not be inspected.
+func findUsers(resources []Resource) (int, error) {
+	byPath := make(map[string]*Resource)
+	for i := range resources {
+		if r := &resources[i]; r.Kind == SharedMemoryResource {
+			byPath[r.Path] = r
+		}
+	}
+	procs, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list processes")
+	}
+	self := os.Getpid()
+	var uninspected int
+	for _, proc := range procs {
+		pid, err := strconv.Atoi(proc.Name())
+		if err != nil || !proc.IsDir() {
+			continue
+		}
+		paths, err := processFiles(pid)
+		if err != nil {
+			// the process may have exited in meanwhile.
+			if !os.IsNotExist(err) && pid != self {
+				uninspected++
+			}
+			continue
+		}
+		for path := range paths {
+			if r, ok := byPath[path]; ok {
+				r.Pids = append(r.Pids, pid)
+			}
+		}
+	}
+	return uninspected, nil
+}
+
+// processFiles returns paths of all files, mapped or opened by the process.
+func processFiles(pid int) (map[string]struct{}, error) {
+	procDir := "/proc/" + strconv.Itoa(pid)
+	result := make(map[string]struct{})
+	maps, err := os.Open(procDir + "/maps")
+	if err != nil {
+		return nil, err
+	}
+	defer maps.Close()
+	scanner := bufio.NewScanner(maps)
+	for scanner.Scan() {
+		// address perms offset dev inode pathname
+		fields := strings.SplitN(scanner.Text(), " ", 6)
+		if len(fields) < 6 {
+			continue
+		}
+		if path := strings.TrimSpace(fields[5]); strings.HasPrefix(path, "/") {
+			result[path] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	fds, err := ioutil.ReadDir(procDir + "/fd")
+	if err != nil {
+		return nil, err
+	}
+	for _, fd := range fds {
+		if path, err := os.Readlink(procDir + "/fd/" + fd.Name()); err == nil {
+			result[path] = struct{}{}
+		}
+	}
+	return result, nil
+}
+
+func destroyResource(r *Resource) error {
+	switch r.Kind {
+	case SharedMemoryResource:
+		return shm.DestroyMemoryObject(r.Name)
+	case SysVSemaphoreResource:
+		return ignoreNotExist(sysvRemove(r.Kind, r.ID))
+	case SysVMessageQueueResource:
+		return ignoreNotExist(sysvRemove(r.Kind, r.ID))
+	default:
+		return ignoreNotExist(os.Remove(r.Path))
+	}
+}
+
+func ignoreNotExist(err error) error {
+	if os.IsNotExist(err) || errors.Cause(err) == unix.EINVAL || errors.Cause(err) == unix.EIDRM {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,131 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package ipc
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nxgtw/go-ipc/mq"
+	"github.com/nxgtw/go-ipc/shm"
+	ipc_sync "github.com/nxgtw/go-ipc/sync"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testInventoryMqName    = "go-ipc.inventory-test.mq"
+	testInventoryMutexName = "go-ipc.inventory-test.rw"
+)
+
+func findObject(a *assert.Assertions, name, typ string) *Object {
+	inv, err := ListObjects()
+	if !a.NoError(err) {
+		return nil
+	}
+	for i := range inv.Objects {
+		if o := &inv.Objects[i]; o.Name == name && o.Type == typ {
+			return o
+		}
+	}
+	return nil
+}
+
+func TestListObjects(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(mq.DestroyFastMq(testInventoryMqName)) || !a.NoError(ipc_sync.DestroyRWMutex(testInventoryMutexName)) {
+		return
+	}
+	q, err := mq.CreateFastMq(testInventoryMqName, os.O_EXCL, 0666, 1, 8)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.DestroyFastMq(testInventoryMqName)
+	m, err := ipc_sync.NewRWMutex(testInventoryMutexName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		q.Close()
+		return
+	}
+	defer ipc_sync.DestroyRWMutex(testInventoryMutexName)
+	o := findObject(a, testInventoryMqName, FastMqType)
+	if a.NotNil(o) {
+		a.Equal([]int{os.Getpid()}, o.Owners())
+		a.False(o.Orphan())
+		a.True(o.Age() < time.Minute)
+	}
+	o = findObject(a, testInventoryMutexName, RWMutexType)
+	if a.NotNil(o) {
+		a.Equal(1, o.Attached())
+	}
+	a.NoError(q.Close())
+	a.NoError(m.Close())
+	o = findObject(a, testInventoryMqName, FastMqType)
+	if a.NotNil(o) {
+		a.True(o.Orphan())
+		a.NoError(o.Destroy())
+	}
+	a.Nil(findObject(a, testInventoryMqName, FastMqType))
+}
+
+func TestDestroyOrphans(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(ipc_sync.DestroyFutexMutex(testInventoryMutexName)) {
+		return
+	}
+	m, err := ipc_sync.NewFutexMutex(testInventoryMutexName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer ipc_sync.DestroyFutexMutex(testInventoryMutexName)
+	inv, err := ListObjects()
+	if !a.NoError(err) {
+		return
+	}
+	if inv.Uninspected > 0 {
+		t.Skip("some processes can't be inspected")
+	}
+	a.NoError(m.Close())
+	o := findObject(a, testInventoryMutexName, FutexMutexType)
+	if !a.NotNil(o) {
+		return
+	}
+	// make the object look old.
+	old := time.Now().Add(-time.Hour * 24)
+	for _, r := range o.Resources {
+		if r.Kind == SharedMemoryResource {
+			a.NoError(os.Chtimes(r.Path, old, old))
+		}
+	}
+	destroyed, err := DestroyOrphans(time.Hour)
+	a.NoError(err)
+	var found bool
+	for _, o := range destroyed {
+		found = found || o.Name == testInventoryMutexName
+	}
+	a.True(found)
+	a.Nil(findObject(a, testInventoryMutexName, FutexMutexType))
+	_, err = shm.NewMemoryObject(testInventoryMutexName+".sf", os.O_RDWR, 0666)
+	a.Error(err)
+}
+
+func TestListObjectsNamespace(t *testing.T) {
+	a := assert.New(t)
+	const name = "go-ipc.inventory-test.ns"
+	if !a.NoError(shm.SetNamespace("inventory-ns")) {
+		return
+	}
+	defer shm.SetNamespace("")
+	obj, err := shm.NewMemoryObject(name, os.O_CREATE|os.O_RDWR, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer obj.Destroy()
+	if o := findObject(a, name, MemoryObjectType); a.NotNil(o) {
+		a.Equal(name, o.Resources[0].Name)
+	}
+	a.NoError(shm.SetNamespace(""))
+	a.Nil(findObject(a, name, MemoryObjectType))
+	a.NotNil(findObject(a, "inventory-ns."+name, MemoryObjectType))
+	a.NoError(shm.SetNamespace("inventory-ns"))
+}
@@ -0,0 +1,17 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build !linux
+
+package ipc
+
+import (
+	"github.com/pkg/errors"
+)
+
+func listResources() ([]Resource, int, error) {
+	return nil, 0, errors.New("listing ipc objects is not supported on this platform")
+}
+
+func destroyResource(r *Resource) error {
+	return errors.New("destroying ipc objects is not supported on this platform")
+}
@@ -0,0 +1,27 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build linux,!386
+
+package ipc
+
+import (
+	"syscall"
+
+	"github.com/nxgtw/go-ipc/internal/common"
+
+	"golang.org/x/sys/unix"
+)
+
+// sysvRemove removes a SysV semaphore or a message queue by its id.
+func sysvRemove(kind ResourceKind, id int) error {
+	var err syscall.Errno
+	if kind == SysVSemaphoreResource {
+		_, _, err = unix.Syscall6(unix.SYS_SEMCTL, uintptr(id), 0, common.IpcRmid, 0, 0, 0)
+	} else {
+		_, _, err = unix.Syscall(unix.SYS_MSGCTL, uintptr(id), common.IpcRmid, 0)
+	}
+	if err != syscall.Errno(0) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package ipc
+
+import (
+	"syscall"
+
+	"github.com/nxgtw/go-ipc/internal/common"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	cSEMCTL = 3
+	cMSGCTL = 14
+)
+
+// sysvRemove removes a SysV semaphore or a message queue by its id.
+// on 386 all SysV calls are multiplexed by ipc syscall.
+func sysvRemove(kind ResourceKind, id int) error {
+	var err syscall.Errno
+	if kind == SysVSemaphoreResource {
+		_, _, err = unix.Syscall6(unix.SYS_IPC, cSEMCTL, uintptr(id), 0, common.IpcRmid, 0, 0)
+	} else {
+		_, _, err = unix.Syscall(unix.SYS_IPC, cMSGCTL, uintptr(id), common.IpcRmid)
+	}
+	if err != syscall.Errno(0) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package ipc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func shmResources(names ...string) []Resource {
+	result := make([]Resource, len(names))
+	for i, name := range names {
+		result[i] = Resource{Kind: SharedMemoryResource, Name: name, ID: -1}
+	}
+	return result
+}
+
+func TestGroupResources(t *testing.T) {
+	a := assert.New(t)
+	resources := shmResources(
+		"mq.st", "mq.m.sf", "mq.m.stf", "mq.cvs.st", "mq.cvr.st", "mq.cvr.stcond",
+		"rw.srw", "rw.strw",
+		"go-ipc.spin.spin",
+		"cond.st", "cond.m.ss",
+		"plain",
+		"sema.sema",
+		"rec.srec",
+	)
+	resources = append(resources,
+		Resource{Kind: KeyFileResource, Name: "cond.m"},
+		Resource{Kind: SysVSemaphoreResource, Name: "cond.m", ID: 1},
+		Resource{Kind: KeyFileResource, Name: "rec.recs"},
+		Resource{Kind: SysVMessageQueueResource, Name: "msg", ID: 2},
+		Resource{Kind: KeyFileResource, Name: "msg"},
+	)
+	objects := groupResources(resources)
+	types := make(map[string]string)
+	counts := make(map[string]int)
+	for _, o := range objects {
+		types[o.Name] = o.Type
+		counts[o.Name] = len(o.Resources)
+	}
+	a.Equal(map[string]string{
+		"mq":    FastMqType,
+		"rw":    RWMutexType,
+		"spin":  SpinMutexType,
+		"cond":  CondType,
+		"plain": MemoryObjectType,
+		"sema":  SemaphoreType,
+		"rec":   RecursiveMutexType,
+		"msg":   SysVMessageQueueType,
+	}, types)
+	a.Equal(map[string]int{
+		"mq":    6,
+		"rw":    2,
+		"spin":  1,
+		"cond":  4,
+		"plain": 1,
+		"sema":  1,
+		"rec":   2,
+		"msg":   2,
+	}, counts)
+}
+
+func TestObjectAttached(t *testing.T) {
+	a := assert.New(t)
+	o := Object{Resources: []Resource{{Kind: SysVSemaphoreResource}}}
+	a.Equal(-1, o.Attached())
+	a.False(o.Orphan())
+	// a key file of a SysV object does not make it an orphan.
+	o.Resources = append(o.Resources, Resource{Kind: KeyFileResource})
+	a.Equal(-1, o.Attached())
+	a.False(o.Orphan())
+	o = Object{Resources: []Resource{{Kind: SharedMemoryResource}}}
+	a.Equal(0, o.Attached())
+	a.True(o.Orphan())
+	o.Resources = append(o.Resources, Resource{Kind: SharedMemoryResource, Pids: []int{2, 1}})
+	o.Resources = append(o.Resources, Resource{Kind: SharedMemoryResource, Pids: []int{1}})
+	a.Equal(2, o.Attached())
+	a.Equal([]int{1, 2}, o.Owners())
+}
@@ -0,0 +1,193 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+
+	"github.com/pkg/errors"
+)
+
+// Atomic32 is a 32-bit word of a memory region, which is accessed atomically.
+// It can be used for counters and flags shared between processes.
+// Atomic32 holds a reference to the region, so the former can't be gc'ed while the word is used.
+type Atomic32 struct {
+	region *MemoryRegion
+	off    int
+}
+
+// Atomic64 is a 64-bit word of a memory region, which is accessed atomically.
+// It can be used for counters and flags shared between processes.
+// Atomic64 holds a reference to the region, so the former can't be gc'ed while the word is used.
+type Atomic64 struct {
+	region *MemoryRegion
+	off    int
+}
+
+// NewAtomic32 returns a 32-bit word at the given offset of the region.
+// The address of the word must be 4-byte aligned.
+func NewAtomic32(region *MemoryRegion, off int) (*Atomic32, error) {
+	if err := checkAtomicOffset(region, off, 4); err != nil {
+		return nil, err
+	}
+	return &Atomic32{region: region, off: off}, nil
+}
+
+// NewAtomic64 returns a 64-bit word at the given offset of the region.
+// The address of the word must be 8-byte aligned, even on 32-bit platforms.
+func NewAtomic64(region *MemoryRegion, off int) (*Atomic64, error) {
+	if err := checkAtomicOffset(region, off, 8); err != nil {
+		return nil, err
+	}
+	return &Atomic64{region: region, off: off}, nil
+}
+
+// checkAtomicOffset ensures, that a word of the given size at the offset is inside the region and aligned.
+func checkAtomicOffset(region *MemoryRegion, off int, size int) error {
+	if off < 0 || off > region.Size()-size {
+		return errors.Errorf("%d-byte word at offset %d is out of a %d bytes long region", size, off, region.Size())
+	}
+	if addr := uintptr(allocator.ByteSliceData(region.Data())) + uintptr(off); addr%uintptr(size) != 0 {
+		return errors.Errorf("offset %d is not aligned for a %d-byte word", off, size)
+	}
+	return nil
+}
+
+// atomicPtr returns the address of a word. it is calculated on every access,
+// so that the word remains valid after the region was resized.
+func atomicPtr(region *MemoryRegion, off int, size int) unsafe.Pointer {
+	data := region.Data()
+	if off+size > len(data) {
+		panic(errors.Errorf("word at offset %d is beyond the end of the region", off))
+	}
+	return allocator.AdvancePointer(allocator.ByteSliceData(data), uintptr(off))
+}
+
+func (a *Atomic32) ptr() *int32 {
+	return (*int32)(atomicPtr(a.region, a.off, 4))
+}
+
+// Load atomically loads the value.
+func (a *Atomic32) Load() int32 {
+	result := atomic.LoadInt32(a.ptr())
+	allocator.Use(unsafe.Pointer(a.region))
+	return result
+}
+
+// Store atomically stores the value.
+func (a *Atomic32) Store(value int32) {
+	atomic.StoreInt32(a.ptr(), value)
+	allocator.Use(unsafe.Pointer(a.region))
+}
+
+// Add atomically adds delta to the value and returns the new value.
+func (a *Atomic32) Add(delta int32) int32 {
+	result := atomic.AddInt32(a.ptr(), delta)
+	allocator.Use(unsafe.Pointer(a.region))
+	return result
+}
+
+// CompareAndSwap atomically sets the value to new, if it is equal to old.
+// It returns true, if the value was swapped.
+func (a *Atomic32) CompareAndSwap(old, new int32) bool {
+	result := atomic.CompareAndSwapInt32(a.ptr(), old, new)
+	allocator.Use(unsafe.Pointer(a.region))
+	return result
+}
+
+// Swap atomically sets the value to new and returns the old value.
+func (a *Atomic32) Swap(new int32) int32 {
+	result := atomic.SwapInt32(a.ptr(), new)
+	allocator.Use(unsafe.Pointer(a.region))
+	return result
+}
+
+// Or atomically sets the bits of the mask and returns the old value.
+func (a *Atomic32) Or(mask int32) int32 {
+	p := a.ptr()
+	for {
+		old := atomic.LoadInt32(p)
+		if atomic.CompareAndSwapInt32(p, old, old|mask) {
+			allocator.Use(unsafe.Pointer(a.region))
+			return old
+		}
+	}
+}
+
+// And atomically clears the bits, which are not set in the mask, and returns the old value.
+func (a *Atomic32) And(mask int32) int32 {
+	p := a.ptr()
+	for {
+		old := atomic.LoadInt32(p)
+		if atomic.CompareAndSwapInt32(p, old, old&mask) {
+			allocator.Use(unsafe.Pointer(a.region))
+			return old
+		}
+	}
+}
+
+func (a *Atomic64) ptr() *int64 {
+	return (*int64)(atomicPtr(a.region, a.off, 8))
+}
+
+// Load atomically loads the value.
+func (a *Atomic64) Load() int64 {
+	result := atomic.LoadInt64(a.ptr())
+	allocator.Use(unsafe.Pointer(a.region))
+	return result
+}
+
+// Store atomically stores the value.
+func (a *Atomic64) Store(value int64) {
+	atomic.StoreInt64(a.ptr(), value)
+	allocator.Use(unsafe.Pointer(a.region))
+}
+
+// Add atomically adds delta to the value and returns the new value.
+func (a *Atomic64) Add(delta int64) int64 {
+	result := atomic.AddInt64(a.ptr(), delta)
+	allocator.Use(unsafe.Pointer(a.region))
+	return result
+}
+
+// CompareAndSwap atomically sets the value to new, if it is equal to old.
+// It returns true, if the value was swapped.
+func (a *Atomic64) CompareAndSwap(old, new int64) bool {
+	result := atomic.CompareAndSwapInt64(a.ptr(), old, new)
+	allocator.Use(unsafe.Pointer(a.region))
+	return result
+}
+
+// Swap atomically sets the value to new and returns the old value.
+func (a *Atomic64) Swap(new int64) int64 {
+	result := atomic.SwapInt64(a.ptr(), new)
+	allocator.Use(unsafe.Pointer(a.region))
+	return result
+}
+
+// Or atomically sets the bits of the mask and returns the old value.
+func (a *Atomic64) Or(mask int64) int64 {
+	p := a.ptr()
+	for {
+		old := atomic.LoadInt64(p)
+		if atomic.CompareAndSwapInt64(p, old, old|mask) {
+			allocator.Use(unsafe.Pointer(a.region))
+			return old
+		}
+	}
+}
+
+// And atomically clears the bits, which are not set in the mask, and returns the old value.
+func (a *Atomic64) And(mask int64) int64 {
+	p := a.ptr()
+	for {
+		old := atomic.LoadInt64(p)
+		if atomic.CompareAndSwapInt64(p, old, old&mask) {
+			allocator.Use(unsafe.Pointer(a.region))
+			return old
+		}
+	}
+}
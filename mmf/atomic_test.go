@@ -0,0 +1,87 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtomic32(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup := newTestRegion(t, 64)
+	defer cleanup()
+	_, err := NewAtomic32(region, 2)
+	a.Error(err)
+	_, err = NewAtomic32(region, 62)
+	a.Error(err)
+	_, err = NewAtomic32(region, -4)
+	a.Error(err)
+	v, err := NewAtomic32(region, 60)
+	if !a.NoError(err) {
+		return
+	}
+	v.Store(5)
+	a.Equal(int32(5), v.Load())
+	a.Equal(int32(8), v.Add(3))
+	a.False(v.CompareAndSwap(5, 1))
+	a.True(v.CompareAndSwap(8, 1))
+	a.Equal(int32(1), v.Swap(0x10))
+	a.Equal(int32(0x10), v.Or(0x3))
+	a.Equal(int32(0x13), v.And(0x6))
+	a.Equal(int32(0x2), v.Load())
+	another, err := NewAtomic32(region, 60)
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(int32(0x2), another.Load())
+}
+
+func TestAtomic64(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup := newTestRegion(t, 64)
+	defer cleanup()
+	_, err := NewAtomic64(region, 4)
+	a.Error(err)
+	_, err = NewAtomic64(region, 64)
+	a.Error(err)
+	v, err := NewAtomic64(region, 8)
+	if !a.NoError(err) {
+		return
+	}
+	v.Store(1 << 40)
+	a.Equal(int64(1<<40), v.Load())
+	a.Equal(int64(1<<40-1), v.Add(-1))
+	a.True(v.CompareAndSwap(1<<40-1, 0))
+	a.Equal(int64(0), v.Swap(0xf0))
+	a.Equal(int64(0xf0), v.Or(0x0f))
+	a.Equal(int64(0xff), v.And(0x3c))
+	a.Equal(int64(0x3c), v.Load())
+}
+
+func TestAtomicConcurrent(t *testing.T) {
+	const (
+		workers = 8
+		count   = 10000
+	)
+	region, cleanup := newTestRegion(t, 64)
+	defer cleanup()
+	v, err := NewAtomic64(region, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < count; j++ {
+				v.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(workers*count), v.Load())
+}
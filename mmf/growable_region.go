@@ -0,0 +1,234 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"os"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/common"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// GrowableRegionHeaderSize is the size of the header at the beginning of a growable region's object.
+	GrowableRegionHeaderSize = 64
+)
+
+// growableHeader is stored at the beginning of the object.
+type growableHeader struct {
+	// size is the size of the data after the header.
+	size int64
+	// generation is incremented every time the size is changed.
+	generation uint32
+	// guard is the pid of the process, which is changing the size.
+	guard int32
+}
+
+// ResizableObject is an object, which can be mapped, and whose size can be changed.
+// shm.MemoryObject satisfies this interface.
+type ResizableObject interface {
+	Mappable
+	SizedObject
+	Truncate(size int64) error
+}
+
+// GrowableRegion is a mapping of a memory object, which can grow.
+// The object starts with a header, which holds the current size of the data and a generation counter.
+// When one process grows the object, other processes detect it with a cheap check of the generation
+// in Changed, Refresh, or Ensure, and remap the object. On linux the object is remapped with mremap.
+// The data, returned by Data(), must not be used after the region was remapped.
+type GrowableRegion struct {
+	obj        ResizableObject
+	flag       int
+	region     *MemoryRegion
+	hdr        *growableHeader
+	generation uint32
+}
+
+// NewGrowableRegion maps a growable memory object.
+// If the object is empty, it is truncated and initialized with the given data size.
+// Otherwise, its current size is used.
+//	obj - an object to map. It must stay open while the region is used.
+//	flag - MEM_READWRITE or MEM_READ_ONLY. Read only regions can't grow, but they can be refreshed.
+//	size - initial data size for an object, which has not been initialized yet.
+func NewGrowableRegion(obj ResizableObject, flag int, size int) (*GrowableRegion, error) {
+	if flag != MEM_READWRITE && flag != MEM_READ_ONLY {
+		return nil, errors.Errorf("invalid growable region flags %d", flag)
+	}
+	if size < 0 {
+		return nil, errors.Errorf("invalid region size %d", size)
+	}
+	result := &GrowableRegion{obj: obj, flag: flag}
+	if obj.Size() == 0 {
+		if flag != MEM_READWRITE {
+			return nil, errors.New("an empty object can't be mapped read only")
+		}
+		if err := obj.Truncate(GrowableRegionHeaderSize); err != nil {
+			return nil, errors.Wrap(err, "failed to truncate the object")
+		}
+	} else if obj.Size() < GrowableRegionHeaderSize {
+		return nil, errors.New("the object is too small for a growable region")
+	}
+	if err := result.remap(GrowableRegionHeaderSize); err != nil {
+		return nil, err
+	}
+	if _, err := result.Refresh(); err != nil {
+		result.Close()
+		return nil, err
+	}
+	if size > 0 && flag == MEM_READWRITE && atomic.LoadInt64(&result.hdr.size) == 0 {
+		if err := result.Grow(size); err != nil {
+			result.Close()
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// Data returns the data of the region without the header.
+// It must not be used after the region was remapped by Grow, Refresh, or Ensure.
+func (g *GrowableRegion) Data() []byte {
+	return g.region.Data()[GrowableRegionHeaderSize:]
+}
+
+// Size returns the size of the data, which is mapped in the current process.
+func (g *GrowableRegion) Size() int {
+	return g.region.Size() - GrowableRegionHeaderSize
+}
+
+// Generation returns the generation of the region, which is mapped in the current process.
+func (g *GrowableRegion) Generation() uint32 {
+	return g.generation
+}
+
+// Changed returns true, if the region was resized, and the current process has not remapped it yet.
+func (g *GrowableRegion) Changed() bool {
+	return atomic.LoadUint32(&g.hdr.generation) != g.generation
+}
+
+// Refresh remaps the region, if it was resized by another process.
+// It returns true, if the region was remapped.
+func (g *GrowableRegion) Refresh() (bool, error) {
+	// the generation is loaded first, as it is increased after the size.
+	// so, the size is not less, than the size of the generation.
+	gen := atomic.LoadUint32(&g.hdr.generation)
+	if gen == g.generation {
+		return false, nil
+	}
+	size := atomic.LoadInt64(&g.hdr.size)
+	if err := g.remap(GrowableRegionHeaderSize + int(size)); err != nil {
+		return false, err
+	}
+	g.generation = gen
+	return true, nil
+}
+
+// Ensure makes sure, that at least n bytes of data are mapped, remapping the region if needed.
+// It is cheap, if the region is large enough, so it can be called before accessing the data.
+func (g *GrowableRegion) Ensure(n int) error {
+	if n <= g.Size() {
+		return nil
+	}
+	if _, err := g.Refresh(); err != nil {
+		return err
+	}
+	if n > g.Size() {
+		return errors.Errorf("the region is %d bytes long, %d bytes are required", g.Size(), n)
+	}
+	return nil
+}
+
+// Grow resizes the object, so that it holds at least size bytes of data, and remaps the region.
+// The region never shrinks, so if it is already larger, it is only refreshed.
+func (g *GrowableRegion) Grow(size int) error {
+	if g.flag != MEM_READWRITE {
+		return errors.New("a read only region can't grow")
+	}
+	if int64(size) > atomic.LoadInt64(&g.hdr.size) {
+		if err := g.grow(int64(size)); err != nil {
+			return err
+		}
+	}
+	_, err := g.Refresh()
+	return err
+}
+
+func (g *GrowableRegion) grow(size int64) error {
+	g.lock()
+	defer g.unlock()
+	if size <= atomic.LoadInt64(&g.hdr.size) {
+		return nil
+	}
+	if total := GrowableRegionHeaderSize + size; g.obj.Size() < total {
+		if err := g.obj.Truncate(total); err != nil {
+			return errors.Wrap(err, "failed to truncate the object")
+		}
+	}
+	atomic.StoreInt64(&g.hdr.size, size)
+	atomic.AddUint32(&g.hdr.generation, 1)
+	return nil
+}
+
+// Close unmaps the region. It does not close the object.
+func (g *GrowableRegion) Close() error {
+	if g.region == nil {
+		return nil
+	}
+	err := g.region.Close()
+	g.region, g.hdr = nil, nil
+	return err
+}
+
+// remap maps the object with the given size, resizing the current mapping, if possible.
+func (g *GrowableRegion) remap(size int) error {
+	if g.region != nil {
+		err := g.region.Resize(size)
+		if err == nil {
+			g.setHeader()
+			return nil
+		}
+		if err != errResizeNotSupported {
+			return err
+		}
+	}
+	region, err := NewMemoryRegion(g.obj, g.flag, 0, size)
+	if err != nil {
+		return errors.Wrap(err, "failed to map the object")
+	}
+	if g.region != nil {
+		g.region.Close()
+	}
+	g.region = region
+	g.setHeader()
+	return nil
+}
+
+func (g *GrowableRegion) setHeader() {
+	g.hdr = (*growableHeader)(allocator.ByteSliceData(g.region.Data()))
+}
+
+// lock acquires the guard. if its owner has died, the guard is taken over.
+func (g *GrowableRegion) lock() {
+	pid := int32(os.Getpid())
+	for {
+		owner := atomic.LoadInt32(&g.hdr.guard)
+		if owner == 0 {
+			if atomic.CompareAndSwapInt32(&g.hdr.guard, 0, pid) {
+				return
+			}
+		} else if owner != pid && !common.ProcessAlive(int(owner)) {
+			if atomic.CompareAndSwapInt32(&g.hdr.guard, owner, pid) {
+				return
+			}
+		}
+		runtime.Gosched()
+	}
+}
+
+func (g *GrowableRegion) unlock() {
+	atomic.StoreInt32(&g.hdr.guard, 0)
+}
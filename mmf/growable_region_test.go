@@ -0,0 +1,99 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type sizedFile struct {
+	*os.File
+}
+
+func (f sizedFile) Size() int64 {
+	fi, err := f.Stat()
+	if err != nil {
+		return 0
+	}
+	return fi.Size()
+}
+
+func TestMmfResize(t *testing.T) {
+	a := assert.New(t)
+	file, cleanup := newTestFile(t, 0)
+	defer cleanup()
+	pageSize := os.Getpagesize()
+	if !a.NoError(file.Truncate(int64(pageSize * 3))) {
+		return
+	}
+	region, err := NewMemoryRegion(file, MEM_READWRITE, 0, 100)
+	if !a.NoError(err) {
+		return
+	}
+	defer region.Close()
+	copy(region.Data(), []byte("resize"))
+	err = region.Resize(pageSize*3 - 1)
+	if err == errResizeNotSupported {
+		t.Skip(err)
+	}
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(pageSize*3-1, region.Size())
+	a.Equal(pageSize*3-1, len(region.Data()))
+	a.Equal([]byte("resize"), region.Data()[:6])
+	region.Data()[pageSize*3-2] = 1
+	a.Error(region.Resize(0))
+	a.NoError(region.Close())
+	a.Error(region.Resize(100))
+}
+
+func TestGrowableRegion(t *testing.T) {
+	a := assert.New(t)
+	file, cleanup := newTestFile(t, 0)
+	defer cleanup()
+	_, err := NewGrowableRegion(sizedFile{file}, MEM_READ_ONLY, 0)
+	a.Error(err)
+	writer, err := NewGrowableRegion(sizedFile{file}, MEM_READWRITE, 100)
+	if !a.NoError(err) {
+		return
+	}
+	defer writer.Close()
+	a.Equal(100, writer.Size())
+	a.Equal(int64(GrowableRegionHeaderSize+100), sizedFile{file}.Size())
+	file2, err := os.Open(file.Name())
+	if !a.NoError(err) {
+		return
+	}
+	defer file2.Close()
+	reader, err := NewGrowableRegion(sizedFile{file2}, MEM_READ_ONLY, 0)
+	if !a.NoError(err) {
+		return
+	}
+	defer reader.Close()
+	a.Equal(100, reader.Size())
+	a.Equal(writer.Generation(), reader.Generation())
+	a.False(reader.Changed())
+	a.NoError(writer.Grow(50))
+	a.Equal(100, writer.Size())
+	a.NoError(writer.Grow(10000))
+	a.Equal(10000, writer.Size())
+	writer.Data()[9999] = 42
+	a.True(reader.Changed())
+	a.NoError(reader.Ensure(50))
+	a.Equal(100, reader.Size())
+	a.NoError(reader.Ensure(10000))
+	a.False(reader.Changed())
+	a.Equal(10000, reader.Size())
+	a.Equal(byte(42), reader.Data()[9999])
+	a.Error(reader.Ensure(10001))
+	a.Error(reader.Grow(20000))
+	reopened, err := NewGrowableRegion(sizedFile{file}, MEM_READWRITE, 100)
+	if a.NoError(err) {
+		a.Equal(10000, reopened.Size())
+		a.NoError(reopened.Close())
+	}
+}
@@ -17,10 +17,45 @@ const (
 	MEM_READ_PRIVATE  = 0x00000002
 	MEM_READWRITE     = 0x00000004
 	MEM_COPY_ON_WRITE = 0x00000008
+	// MEM_SEALED can be combined with any of the flags above. It makes NewMemoryRegion check,
+	// that the object is sealed against shrinking, and that the mapping does not exceed its size,
+	// so that accessing the mapping can't cause SIGBUS, even if the object is shared with an untrusted process.
+	// The object must implement SealedObject.
+	MEM_SEALED = 0x00000100
+	// MEM_HUGETLB can be combined with any of the flags above. It makes the region use huge pages (linux only).
+	// The object must be backed by huge pages, for example, opened with shm.O_HUGETLB.
+	// Offset must be a multiple of the huge page size.
+	MEM_HUGETLB = 0x00000200
+	// MEM_PREFAULT can be combined with any of the flags above. It makes NewMemoryRegion populate
+	// the pages of the region, so that the first access to them does not cause page faults.
+	// On linux it uses MAP_POPULATE, on other platforms the pages are read after mapping.
+	MEM_PREFAULT = 0x00000400
+
+	// sealShrink is F_SEAL_SHRINK seal. it is supported on linux only.
+	sealShrink = 0x0002
+)
+
+// Advice values for MemoryRegion.Advise.
+const (
+	// MADV_NORMAL means no special treatment.
+	MADV_NORMAL = iota
+	// MADV_RANDOM means, that the pages are going to be accessed in random order.
+	MADV_RANDOM
+	// MADV_SEQUENTIAL means, that the pages are going to be accessed in sequential order.
+	MADV_SEQUENTIAL
+	// MADV_WILLNEED means, that the pages are going to be accessed soon, so they can be read ahead.
+	MADV_WILLNEED
+	// MADV_DONTNEED means, that the pages are not going to be accessed soon, so they can be freed.
+	// Shared mappings keep the data, it is read again on the next access.
+	MADV_DONTNEED
+	// MADV_HUGEPAGE enables transparent huge pages for the region. It is supported on linux only.
+	MADV_HUGEPAGE
 )
 
 var (
 	mmapOffsetMultiple int64
+
+	errResizeNotSupported = errors.New("resizing of memory regions is not supported")
 )
 
 // MemoryRegion is a mmapped area of a memory object.
@@ -33,7 +68,7 @@ var (
 // 		return g(region.Data())
 // 	}
 // region may be gc'ed while its data is used by g().
-// To avoid this, you can use UseMemoryRegion() or region readers/writers.
+// To avoid this, you can use MemoryRegionFile, region slices, or readers/writers.
 type MemoryRegion struct {
 	*memoryRegion
 }
@@ -50,6 +85,12 @@ type Mappable interface {
 // 	offset - offset in bytes from the beginning of the mmaped file.
 // 	size - mapping size.
 func NewMemoryRegion(object Mappable, flag int, offset int64, size int) (*MemoryRegion, error) {
+	if flag&MEM_SEALED != 0 {
+		if err := checkSeals(object, offset, size); err != nil {
+			return nil, err
+		}
+		flag &^= MEM_SEALED
+	}
 	impl, err := newMemoryRegion(object, flag, offset, size)
 	if err != nil {
 		return nil, err
@@ -68,6 +109,7 @@ func (region *MemoryRegion) Close() error {
 
 // Data returns region's mapped data.
 // This function can be dangerous and could be removed in future releases.
+// Consider using MemoryRegionFile or Slice instead.
 func (region *MemoryRegion) Data() []byte {
 	return region.memoryRegion.Data()
 }
@@ -82,6 +124,51 @@ func (region *MemoryRegion) Size() int {
 	return region.memoryRegion.Size()
 }
 
+// Resize changes the size of the mapping. It is supported on linux only, where it uses mremap.
+// The object must be large enough to hold the new size.
+// The mapping may be moved to another address, so the data, returned by Data(), must not be used after the call.
+func (region *MemoryRegion) Resize(size int) error {
+	if size <= 0 {
+		return errors.Errorf("invalid region size %d", size)
+	}
+	return region.memoryRegion.resize(size)
+}
+
+// Lock locks the pages of the region in memory, so that they are not swapped out.
+// It may require privileges or a raised memlock limit.
+func (region *MemoryRegion) Lock() error {
+	return region.memoryRegion.lock()
+}
+
+// Unlock unlocks the pages of the region, which were locked with Lock.
+func (region *MemoryRegion) Unlock() error {
+	return region.memoryRegion.unlock()
+}
+
+// Advise tells the os how the region is going to be used. See MADV_* constants.
+// On windows the advice is ignored.
+func (region *MemoryRegion) Advise(advice int) error {
+	if advice < MADV_NORMAL || advice > MADV_HUGEPAGE {
+		return errors.Errorf("invalid advice %d", advice)
+	}
+	return region.memoryRegion.advise(advice)
+}
+
+// Prefault reads every page of the region, so that subsequent accesses to them do not cause page faults.
+func (region *MemoryRegion) Prefault() {
+	prefault(region.memoryRegion.data)
+}
+
+// Protect changes the access to the region. It can be used, for example,
+// to make a region read only after it was initialized.
+//	flag - MEM_READ_ONLY or MEM_READWRITE.
+func (region *MemoryRegion) Protect(flag int) error {
+	if flag != MEM_READ_ONLY && flag != MEM_READWRITE {
+		return errors.Errorf("invalid protection flags %d", flag)
+	}
+	return region.memoryRegion.protect(flag)
+}
+
 // UseMemoryRegion ensures, that the object is still alive at the moment of the call.
 // The usecase is when you use memory region's Data() and don't use the
 // region itself anymore. In this case the region can be gc'ed, the memory mapping
@@ -91,12 +178,22 @@ func (region *MemoryRegion) Size() int {
 //	defer UseMemoryRegion(region)
 // 	data := region.Data()
 //	{ work with data }
-// However, it is better to use MemoryRegionReader/Writer.
+// However, it is better to use MemoryRegionFile or MemoryRegionReader/Writer.
 // This function could be removed in future releases.
 func UseMemoryRegion(region *MemoryRegion) {
 	allocator.Use(unsafe.Pointer(region))
 }
 
+// prefault reads a byte from every page of the data.
+func prefault(data []byte) {
+	var sum byte
+	for i := 0; i < len(data); i += os.Getpagesize() {
+		sum += data[i]
+	}
+	// prevent the compiler from optimizing the reads out.
+	allocator.Use(unsafe.Pointer(&sum))
+}
+
 // calcMmapOffsetFixup returns a value X,
 // so that  offset - X is a valid mmap offset
 // typically the value of the fixup is a memory page size,
@@ -116,6 +213,38 @@ type SizedObject interface {
 	Size() int64
 }
 
+// SealedObject is an object, which allows to obtain its seals.
+type SealedObject interface {
+	Seals() (int, error)
+}
+
+// checkSeals ensures, that the object can't be shrunk, and that the mapping is inside the object.
+// seals can't be removed, so the mapping remains safe after the check.
+func checkSeals(object Mappable, offset int64, size int) error {
+	so, ok := object.(SealedObject)
+	if !ok {
+		return errors.New("the object does not support seals")
+	}
+	seals, err := so.Seals()
+	if err != nil {
+		return errors.Wrap(err, "failed to get seals")
+	}
+	if seals&sealShrink == 0 {
+		return errors.New("the object is not sealed against shrinking")
+	}
+	objSize, err := fileSizeFromFd(object)
+	if err != nil {
+		return errors.Wrap(err, "failed to get object size")
+	}
+	if size == 0 {
+		size = int(objSize)
+	}
+	if offset < 0 || offset+int64(size) > objSize {
+		return errors.Errorf("the mapping [%d, %d) is beyond the end of the %d bytes long sealed object", offset, offset+int64(size), objSize)
+	}
+	return nil
+}
+
 func fileSizeFromFd(f Mappable) (int64, error) {
 	if f.Fd() == ^uintptr(0) {
 		return 0, nil
@@ -0,0 +1,34 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build darwin freebsd
+
+package mmf
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	mapHugeTLB   = 0
+	mapPopulate  = 0
+	madvHugePage = -1
+)
+
+func (region *memoryRegion) resize(size int) error {
+	return errResizeNotSupported
+}
+
+// syscalls
+
+func mmap(fd uintptr, offset int64, length, prot, flags int) ([]byte, error) {
+	return unix.Mmap(int(fd), offset, length, prot, flags)
+}
+
+func munmap(data []byte) error {
+	if err := unix.Munmap(data); err != nil {
+		return os.NewSyscallError("munmap", err)
+	}
+	return nil
+}
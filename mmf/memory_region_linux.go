@@ -0,0 +1,74 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	mapHugeTLB      = unix.MAP_HUGETLB
+	mapPopulate     = unix.MAP_POPULATE
+	madvHugePage    = unix.MADV_HUGEPAGE
+	cMREMAP_MAYMOVE = 0x1
+)
+
+func (region *memoryRegion) resize(size int) error {
+	if region.data == nil {
+		return errors.New("the region is closed")
+	}
+	length := size + int(region.pageOffset)
+	if region.hugePageSize > 0 {
+		length = int((int64(length) + region.hugePageSize - 1) / region.hugePageSize * region.hugePageSize)
+	}
+	addr, err := mremap(region.data, length, cMREMAP_MAYMOVE)
+	if err != nil {
+		return errors.Wrap(err, "failed to resize the region")
+	}
+	region.data = allocator.ByteSliceFromUnsafePointer(addr, length, length)
+	region.size = size
+	return nil
+}
+
+// syscalls
+// mmap and munmap are called directly rather than via x/sys, as the latter
+// doesn't know about mappings, which were moved by mremap.
+
+func mmap(fd uintptr, offset int64, length, prot, flags int) ([]byte, error) {
+	if length <= 0 {
+		return nil, unix.EINVAL
+	}
+	addr, err := mmapSys(uintptr(length), prot, flags, fd, offset)
+	if err != nil {
+		return nil, err
+	}
+	return allocator.ByteSliceFromUnsafePointer(addr, length, length), nil
+}
+
+func munmap(data []byte) error {
+	dataPointer := unsafe.Pointer(&data[0])
+	_, _, err := unix.Syscall(unix.SYS_MUNMAP, uintptr(dataPointer), uintptr(len(data)), 0)
+	allocator.Use(dataPointer)
+	if err != syscall.Errno(0) {
+		return os.NewSyscallError("munmap", err)
+	}
+	return nil
+}
+
+func mremap(data []byte, newSize int, flags int) (unsafe.Pointer, error) {
+	dataPointer := unsafe.Pointer(&data[0])
+	addr, _, err := unix.Syscall6(unix.SYS_MREMAP, uintptr(dataPointer), uintptr(len(data)), uintptr(newSize), uintptr(flags), 0, 0)
+	allocator.Use(dataPointer)
+	if err != syscall.Errno(0) {
+		return nil, os.NewSyscallError("mremap", err)
+	}
+	// the conversion is done via a pointer to avoid 'possible misuse of unsafe.Pointer' warning.
+	return *(*unsafe.Pointer)(unsafe.Pointer(&addr)), nil
+}
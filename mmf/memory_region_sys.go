@@ -0,0 +1,21 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build linux,!386,!arm,!mips,!mipsle
+
+package mmf
+
+import (
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+func mmapSys(length uintptr, prot, flags int, fd uintptr, offset int64) (unsafe.Pointer, error) {
+	addr, _, err := unix.Syscall6(unix.SYS_MMAP, 0, length, uintptr(prot), uintptr(flags), fd, uintptr(offset))
+	if err != syscall.Errno(0) {
+		return nil, err
+	}
+	// the conversion is done via a pointer to avoid 'possible misuse of unsafe.Pointer' warning.
+	return *(*unsafe.Pointer)(unsafe.Pointer(&addr)), nil
+}
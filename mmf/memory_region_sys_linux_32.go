@@ -0,0 +1,27 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build linux,386 linux,arm linux,mips linux,mipsle
+
+package mmf
+
+import (
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// mmap2 takes the offset in 4096-byte units, so that 64-bit offsets could be passed on 32-bit platforms.
+const mmap2OffsetUnit = 4096
+
+func mmapSys(length uintptr, prot, flags int, fd uintptr, offset int64) (unsafe.Pointer, error) {
+	if offset%mmap2OffsetUnit != 0 {
+		return nil, unix.EINVAL
+	}
+	addr, _, err := unix.Syscall6(unix.SYS_MMAP2, 0, length, uintptr(prot), uintptr(flags), fd, uintptr(offset/mmap2OffsetUnit))
+	if err != syscall.Errno(0) {
+		return nil, err
+	}
+	// the conversion is done via a pointer to avoid 'possible misuse of unsafe.Pointer' warning.
+	return *(*unsafe.Pointer)(unsafe.Pointer(&addr)), nil
+}
@@ -10,6 +10,7 @@ import (
 	"unsafe"
 
 	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/common"
 
 	"github.com/pkg/errors"
 	"golang.org/x/sys/unix"
@@ -23,13 +24,26 @@ type memoryRegion struct {
 	data       []byte
 	size       int
 	pageOffset int64
+	// hugePageSize is not 0 for regions backed by huge pages.
+	hugePageSize int64
 }
 
 func newMemoryRegion(obj Mappable, flag int, offset int64, size int) (*memoryRegion, error) {
-	prot, flags, err := memProtAndFlagsFromMode(flag)
+	prot, flags, err := memProtAndFlagsFromMode(flag &^ (MEM_HUGETLB | MEM_PREFAULT))
 	if err != nil {
 		return nil, errors.Wrap(err, "memory region flags check failed")
 	}
+	// objects backed by huge pages must be mapped and unmapped by whole huge pages.
+	hugeSize := common.HugePageSize(obj.Fd())
+	if flag&MEM_HUGETLB != 0 {
+		if hugeSize == 0 {
+			return nil, errors.New("the object is not backed by huge pages")
+		}
+		flags |= mapHugeTLB
+	}
+	if flag&MEM_PREFAULT != 0 {
+		flags |= mapPopulate
+	}
 	if size, err = checkMmapSize(obj, size); err != nil {
 		return nil, errors.Wrap(err, "size check failed")
 	}
@@ -43,16 +57,29 @@ func newMemoryRegion(obj Mappable, flag int, offset int64, size int) (*memoryReg
 		return nil, errors.New("invalid mapping length")
 	}
 	pageOffset := calcMmapOffsetFixup(offset)
+	length := size + int(pageOffset)
+	if hugeSize > 0 {
+		if offset%hugeSize != 0 {
+			return nil, errors.Errorf("offset must be a multiple of the huge page size %d", hugeSize)
+		}
+		pageOffset, length = 0, int((int64(size)+hugeSize-1)/hugeSize*hugeSize)
+	}
 	var data []byte
-	if data, err = unix.Mmap(int(obj.Fd()), offset-pageOffset, size+int(pageOffset), prot, flags); err != nil {
+	if data, err = mmap(obj.Fd(), offset-pageOffset, length, prot, flags); err != nil {
+		if hugeSize > 0 && err == unix.ENOMEM {
+			return nil, errors.Wrap(err, "mmap failed: not enough huge pages, see /proc/sys/vm/nr_hugepages")
+		}
 		return nil, errors.Wrap(err, "mmap failed")
 	}
-	return &memoryRegion{data: data, size: size, pageOffset: pageOffset}, nil
+	if flag&MEM_PREFAULT != 0 && mapPopulate == 0 {
+		prefault(data)
+	}
+	return &memoryRegion{data: data, size: size, pageOffset: pageOffset, hugePageSize: hugeSize}, nil
 }
 
 func (region *memoryRegion) Close() error {
 	if region.data != nil {
-		err := unix.Munmap(region.data)
+		err := munmap(region.data)
 		region.data = nil
 		region.pageOffset = 0
 		region.size = 0
@@ -62,7 +89,7 @@ func (region *memoryRegion) Close() error {
 }
 
 func (region *memoryRegion) Data() []byte {
-	return region.data[region.pageOffset:]
+	return region.data[region.pageOffset : region.pageOffset+int64(region.size)]
 }
 
 func (region *memoryRegion) Flush(async bool) error {
@@ -80,6 +107,56 @@ func (region *memoryRegion) Size() int {
 	return region.size
 }
 
+func (region *memoryRegion) lock() error {
+	if err := unix.Mlock(region.data); err != nil {
+		return errors.Wrap(os.NewSyscallError("mlock", err), "failed to lock the region")
+	}
+	return nil
+}
+
+func (region *memoryRegion) unlock() error {
+	if err := unix.Munlock(region.data); err != nil {
+		return errors.Wrap(os.NewSyscallError("munlock", err), "failed to unlock the region")
+	}
+	return nil
+}
+
+func (region *memoryRegion) advise(advice int) error {
+	var sysAdvice int
+	switch advice {
+	case MADV_NORMAL:
+		sysAdvice = unix.MADV_NORMAL
+	case MADV_RANDOM:
+		sysAdvice = unix.MADV_RANDOM
+	case MADV_SEQUENTIAL:
+		sysAdvice = unix.MADV_SEQUENTIAL
+	case MADV_WILLNEED:
+		sysAdvice = unix.MADV_WILLNEED
+	case MADV_DONTNEED:
+		sysAdvice = unix.MADV_DONTNEED
+	case MADV_HUGEPAGE:
+		if madvHugePage < 0 {
+			return errors.New("MADV_HUGEPAGE is not supported")
+		}
+		sysAdvice = madvHugePage
+	}
+	if err := unix.Madvise(region.data, sysAdvice); err != nil {
+		return errors.Wrap(os.NewSyscallError("madvise", err), "failed to advise the region")
+	}
+	return nil
+}
+
+func (region *memoryRegion) protect(flag int) error {
+	prot := unix.PROT_READ
+	if flag == MEM_READWRITE {
+		prot |= unix.PROT_WRITE
+	}
+	if err := unix.Mprotect(region.data, prot); err != nil {
+		return errors.Wrap(os.NewSyscallError("mprotect", err), "failed to protect the region")
+	}
+	return nil
+}
+
 func memProtAndFlagsFromMode(mode int) (prot, flags int, err error) {
 	switch mode {
 	case MEM_READ_ONLY:
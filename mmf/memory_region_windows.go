@@ -34,6 +34,11 @@ type native interface {
 }
 
 func newMemoryRegion(obj Mappable, mode int, offset int64, size int) (*memoryRegion, error) {
+	if mode&MEM_HUGETLB != 0 {
+		return nil, errors.New("huge pages are not supported")
+	}
+	prefaultData := mode&MEM_PREFAULT != 0
+	mode &^= MEM_PREFAULT
 	prot, flags, err := sysProtAndFlagsFromFlag(mode)
 	if err != nil {
 		return nil, errors.Wrap(err, "memory region flags check failed")
@@ -73,11 +78,15 @@ func newMemoryRegion(obj Mappable, mode int, offset int64, size int) (*memoryReg
 	}
 
 	totalSize := size + int(pageOffset)
-	return &memoryRegion{
+	result := &memoryRegion{
 		data:       allocator.ByteSliceFromUnsafePointer(unsafe.Pointer(addr), totalSize, totalSize),
 		size:       size,
 		pageOffset: pageOffset,
-	}, nil
+	}
+	if prefaultData {
+		prefault(result.data)
+	}
+	return result, nil
 }
 
 func (region *memoryRegion) Close() error {
@@ -98,6 +107,40 @@ func (region *memoryRegion) Size() int {
 	return region.size
 }
 
+func (region *memoryRegion) resize(size int) error {
+	return errResizeNotSupported
+}
+
+func (region *memoryRegion) lock() error {
+	if err := windows.VirtualLock(uintptr(allocator.ByteSliceData(region.data)), uintptr(len(region.data))); err != nil {
+		return errors.Wrap(os.NewSyscallError("VirtualLock", err), "failed to lock the region")
+	}
+	return nil
+}
+
+func (region *memoryRegion) unlock() error {
+	if err := windows.VirtualUnlock(uintptr(allocator.ByteSliceData(region.data)), uintptr(len(region.data))); err != nil {
+		return errors.Wrap(os.NewSyscallError("VirtualUnlock", err), "failed to unlock the region")
+	}
+	return nil
+}
+
+func (region *memoryRegion) advise(advice int) error {
+	return nil
+}
+
+func (region *memoryRegion) protect(flag int) error {
+	prot := uint32(windows.PAGE_READONLY)
+	if flag == MEM_READWRITE {
+		prot = windows.PAGE_READWRITE
+	}
+	var old uint32
+	if err := windows.VirtualProtect(uintptr(allocator.ByteSliceData(region.data)), uintptr(len(region.data)), prot, &old); err != nil {
+		return errors.Wrap(os.NewSyscallError("VirtualProtect", err), "failed to protect the region")
+	}
+	return nil
+}
+
 func (region *memoryRegion) Flush(async bool) error {
 	err := windows.FlushViewOfFile(uintptr(allocator.ByteSliceData(region.data)), uintptr(len(region.data)))
 	if err != nil {
@@ -16,6 +16,24 @@ const (
 	testFile   = testFolder + "test.bin"
 )
 
+// newTestFile creates a temporary file of the given size.
+// the returned func closes and removes it.
+func newTestFile(t *testing.T, size int64) (*os.File, func()) {
+	file, err := ioutil.TempFile("", "mmf-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup := func() {
+		file.Close()
+		os.Remove(file.Name())
+	}
+	if err = file.Truncate(size); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	return file, cleanup
+}
+
 func TestMmfOpen(t *testing.T) {
 	a := assert.New(t)
 	file, err := os.Open(testFile)
@@ -67,6 +85,101 @@ func TestMmfOpenReadonly(t *testing.T) {
 	region.Close()
 }
 
+type sealedFile struct {
+	*os.File
+	seals int
+}
+
+func (f *sealedFile) Seals() (int, error) {
+	return f.seals, nil
+}
+
+func TestMmfSealed(t *testing.T) {
+	a := assert.New(t)
+	file, err := os.Open(testFile)
+	if !a.NoError(err) {
+		return
+	}
+	defer file.Close()
+	_, err = NewMemoryRegion(file, MEM_READ_ONLY|MEM_SEALED, 0, 1024)
+	a.Error(err)
+	_, err = NewMemoryRegion(&sealedFile{File: file}, MEM_READ_ONLY|MEM_SEALED, 0, 1024)
+	a.Error(err)
+	mr, err := NewMemoryRegion(&sealedFile{File: file, seals: sealShrink}, MEM_READ_ONLY|MEM_SEALED, 0, 1024)
+	if a.NoError(err) {
+		a.Equal(1024, mr.Size())
+		a.NoError(mr.Close())
+	}
+	stat, err := file.Stat()
+	if !a.NoError(err) {
+		return
+	}
+	// the mapping must not exceed the object.
+	_, err = NewMemoryRegion(&sealedFile{File: file, seals: sealShrink}, MEM_READ_ONLY|MEM_SEALED, stat.Size()-512, 1024)
+	a.Error(err)
+}
+
+func TestMmfHugeTLBNotSupported(t *testing.T) {
+	file, err := os.Open(testFile)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer file.Close()
+	_, err = NewMemoryRegion(file, MEM_READ_ONLY|MEM_HUGETLB, 0, 1024)
+	assert.Error(t, err)
+}
+
+func TestMmfMemoryControl(t *testing.T) {
+	a := assert.New(t)
+	file, err := os.Open(testFile)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer file.Close()
+	mr, err := NewMemoryRegion(file, MEM_READ_ONLY|MEM_PREFAULT, 0, 0)
+	if !a.NoError(err) {
+		return
+	}
+	defer mr.Close()
+	for _, advice := range []int{MADV_SEQUENTIAL, MADV_RANDOM, MADV_WILLNEED, MADV_DONTNEED, MADV_NORMAL} {
+		a.NoError(mr.Advise(advice))
+	}
+	a.Error(mr.Advise(MADV_HUGEPAGE + 1))
+	mr.Prefault()
+	// locking may fail because of the memlock limit.
+	if err = mr.Lock(); err == nil {
+		a.NoError(mr.Unlock())
+	}
+	a.Error(mr.Protect(MEM_COPY_ON_WRITE))
+	a.NoError(mr.Protect(MEM_READ_ONLY))
+}
+
+func TestMmfProtect(t *testing.T) {
+	a := assert.New(t)
+	file, err := ioutil.TempFile("", "mmf-protect")
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+	if !a.NoError(file.Truncate(4096)) {
+		return
+	}
+	mr, err := NewMemoryRegion(file, MEM_READWRITE, 0, 4096)
+	if !a.NoError(err) {
+		return
+	}
+	defer mr.Close()
+	mr.Data()[0] = 1
+	a.NoError(mr.Protect(MEM_READ_ONLY))
+	a.Equal(byte(1), mr.Data()[0])
+	a.NoError(mr.Protect(MEM_READWRITE))
+	mr.Data()[1] = 2
+	a.Equal(byte(2), mr.Data()[1])
+}
+
 func TestMmfFileCopy(t *testing.T) {
 	a := assert.New(t)
 	inFile, err := os.Open(testFile)
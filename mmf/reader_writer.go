@@ -56,3 +56,13 @@ func (w *MemoryRegionWriter) Write(p []byte) (n int, err error) {
 	w.pos += int64(n)
 	return n, err
 }
+
+// Seek is to implement io.Seeker.
+func (w *MemoryRegionWriter) Seek(offset int64, whence int) (int64, error) {
+	pos, err := seekPos(w.pos, int64(w.region.Size()), offset, whence)
+	if err != nil {
+		return 0, err
+	}
+	w.pos = pos
+	return pos, nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"io"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+
+	"github.com/pkg/errors"
+)
+
+// MemoryRegionSlice is a part of a memory region.
+// It holds a reference to the region, so the former can't be gc'ed while the slice is used.
+// MemoryRegionSlice implements io.ReaderAt and io.WriterAt.
+type MemoryRegionSlice struct {
+	region *MemoryRegion
+	off    int
+	size   int
+}
+
+// Slice returns a part of the region, which starts at off and is length bytes long.
+func (region *MemoryRegion) Slice(off, length int) (*MemoryRegionSlice, error) {
+	return newMemoryRegionSlice(region, 0, region.Size(), off, length)
+}
+
+func newMemoryRegionSlice(region *MemoryRegion, parentOff, parentSize, off, length int) (*MemoryRegionSlice, error) {
+	if off < 0 || length < 0 || off > parentSize || length > parentSize-off {
+		return nil, errors.Errorf("invalid slice [%d, %d) of a %d bytes long region", off, off+length, parentSize)
+	}
+	return &MemoryRegionSlice{region: region, off: parentOff + off, size: length}, nil
+}
+
+// Slice returns a part of the slice, which starts at off and is length bytes long.
+func (s *MemoryRegionSlice) Slice(off, length int) (*MemoryRegionSlice, error) {
+	return newMemoryRegionSlice(s.region, s.off, s.size, off, length)
+}
+
+// Region returns the region of the slice.
+func (s *MemoryRegionSlice) Region() *MemoryRegion {
+	return s.region
+}
+
+// Offset returns the offset of the slice from the beginning of the region.
+func (s *MemoryRegionSlice) Offset() int {
+	return s.off
+}
+
+// Size returns the size of the slice.
+func (s *MemoryRegionSlice) Size() int {
+	return s.size
+}
+
+// ReadAt is to implement io.ReaderAt.
+func (s *MemoryRegionSlice) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if data := s.data(); off < int64(len(data)) {
+		n = copy(p, data[off:])
+	}
+	allocator.Use(unsafe.Pointer(s.region))
+	if n < len(p) {
+		err = io.EOF
+	}
+	return
+}
+
+// WriteAt is to implement io.WriterAt.
+func (s *MemoryRegionSlice) WriteAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if data := s.data(); off < int64(len(data)) {
+		n = copy(data[off:], p)
+	}
+	allocator.Use(unsafe.Pointer(s.region))
+	if n < len(p) {
+		err = io.ErrShortWrite
+	}
+	return
+}
+
+// data returns the bytes of the slice. if the region has been resized,
+// the part of the slice, which is beyond the end of the region, is cut off.
+func (s *MemoryRegionSlice) data() []byte {
+	data := s.region.Data()
+	if s.off >= len(data) {
+		return nil
+	}
+	end := s.off + s.size
+	if end > len(data) {
+		end = len(data)
+	}
+	return data[s.off:end]
+}
+
+// MemoryRegionFile provides file-like access to a memory region or its slice.
+// It implements io.ReadWriteSeeker, io.ReaderAt, and io.WriterAt, and can be used instead of region's Data().
+// It holds a reference to the region, so the former can't be gc'ed.
+type MemoryRegionFile struct {
+	*MemoryRegionSlice
+	pos int64
+}
+
+// NewMemoryRegionFile returns a file for the entire region.
+func NewMemoryRegionFile(region *MemoryRegion) *MemoryRegionFile {
+	return &MemoryRegionFile{MemoryRegionSlice: &MemoryRegionSlice{region: region, size: region.Size()}}
+}
+
+// File returns a file for the slice.
+func (s *MemoryRegionSlice) File() *MemoryRegionFile {
+	return &MemoryRegionFile{MemoryRegionSlice: s}
+}
+
+// Read is to implement io.Reader.
+func (f *MemoryRegionFile) Read(p []byte) (n int, err error) {
+	if f.pos >= int64(f.size) {
+		return 0, io.EOF
+	}
+	n, err = f.ReadAt(p, f.pos)
+	f.pos += int64(n)
+	if n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Write is to implement io.Writer.
+func (f *MemoryRegionFile) Write(p []byte) (n int, err error) {
+	n, err = f.WriteAt(p, f.pos)
+	f.pos += int64(n)
+	return n, err
+}
+
+// Seek is to implement io.Seeker.
+// Seeking beyond the end of the file is allowed, but subsequent reads return io.EOF, and writes return io.ErrShortWrite.
+func (f *MemoryRegionFile) Seek(offset int64, whence int) (int64, error) {
+	pos, err := seekPos(f.pos, int64(f.size), offset, whence)
+	if err != nil {
+		return 0, err
+	}
+	f.pos = pos
+	return pos, nil
+}
+
+// seekPos calculates new position for io.Seeker implementations.
+func seekPos(pos, size, offset int64, whence int) (int64, error) {
+	switch whence {
+	case 0:
+	case 1:
+		offset += pos
+	case 2:
+		offset += size
+	default:
+		return 0, errors.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	return offset, nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRegion(t *testing.T, size int) (*MemoryRegion, func()) {
+	file, cleanup := newTestFile(t, int64(size))
+	region, err := NewMemoryRegion(file, MEM_READWRITE, 0, size)
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	return region, func() {
+		region.Close()
+		cleanup()
+	}
+}
+
+func TestMemoryRegionSlice(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup := newTestRegion(t, 1024)
+	defer cleanup()
+	_, err := region.Slice(1000, 25)
+	a.Error(err)
+	_, err = region.Slice(-1, 10)
+	a.Error(err)
+	s, err := region.Slice(100, 200)
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(100, s.Offset())
+	a.Equal(200, s.Size())
+	inner, err := s.Slice(50, 10)
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(150, inner.Offset())
+	_, err = s.Slice(195, 10)
+	a.Error(err)
+	n, err := inner.WriteAt([]byte("0123456789abc"), 0)
+	a.Equal(10, n)
+	a.Equal(io.ErrShortWrite, err)
+	a.Equal([]byte("0123456789"), region.Data()[150:160])
+	buf := make([]byte, 4)
+	n, err = s.ReadAt(buf, 52)
+	a.Equal(4, n)
+	a.NoError(err)
+	a.Equal([]byte("2345"), buf)
+	n, err = inner.ReadAt(buf, 8)
+	a.Equal(2, n)
+	a.Equal(io.EOF, err)
+	_, err = s.ReadAt(buf, -1)
+	a.Error(err)
+}
+
+func TestMemoryRegionFile(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup := newTestRegion(t, 64)
+	defer cleanup()
+	f := NewMemoryRegionFile(region)
+	n, err := f.Write([]byte("hello, world"))
+	a.Equal(12, n)
+	a.NoError(err)
+	pos, err := f.Seek(7, 0)
+	a.NoError(err)
+	a.Equal(int64(7), pos)
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(f, buf)
+	a.NoError(err)
+	a.Equal([]byte("world"), buf)
+	pos, err = f.Seek(-5, 1)
+	a.NoError(err)
+	a.Equal(int64(7), pos)
+	pos, err = f.Seek(-4, 2)
+	a.NoError(err)
+	a.Equal(int64(60), pos)
+	n, err = f.Write([]byte("abcdef"))
+	a.Equal(4, n)
+	a.Equal(io.ErrShortWrite, err)
+	n, err = f.Read(buf)
+	a.Equal(0, n)
+	a.Equal(io.EOF, err)
+	_, err = f.Seek(-1, 0)
+	a.Error(err)
+	_, err = f.Seek(0, 3)
+	a.Error(err)
+	all, err := ioutil.ReadAll(io.NewSectionReader(f, 0, 64))
+	a.NoError(err)
+	a.Equal([]byte("hello, world"), all[:12])
+	a.Equal([]byte("abcd"), all[60:])
+	s, err := region.Slice(7, 5)
+	if !a.NoError(err) {
+		return
+	}
+	all, err = ioutil.ReadAll(s.File())
+	a.NoError(err)
+	a.Equal([]byte("world"), all)
+}
+
+func TestMemoryRegionWriterSeek(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup := newTestRegion(t, 16)
+	defer cleanup()
+	w := NewMemoryRegionWriter(region)
+	pos, err := w.Seek(-2, 2)
+	a.NoError(err)
+	a.Equal(int64(14), pos)
+	n, err := w.Write([]byte{1, 2, 3})
+	a.Equal(2, n)
+	a.Equal(io.EOF, err)
+	a.Equal([]byte{1, 2}, region.Data()[14:])
+}
@@ -0,0 +1,107 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build go1.18
+
+package mmf
+
+import (
+	"reflect"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+
+	"github.com/pkg/errors"
+)
+
+// View is a typed view of a memory region. It contains one or more values of type T,
+// which are placed one after another starting at some offset of the region.
+// T must be a fixed-layout type without pointers, slices, strings, maps, and so on.
+// View holds a reference to the region, so the former can't be gc'ed while the view is used.
+// The address of the values is calculated on every access, so the view remains valid after the region was resized.
+type View[T any] struct {
+	region *MemoryRegion
+	off    int
+	length int
+}
+
+// NewView returns a view of a single value of type T at the given offset of the region.
+func NewView[T any](region *MemoryRegion, off int) (*View[T], error) {
+	return NewArrayView[T](region, off, 1)
+}
+
+// NewArrayView returns a view of length consecutive values of type T at the given offset of the region.
+// The offset must be properly aligned for T, and the values must fit into the region.
+func NewArrayView[T any](region *MemoryRegion, off int, length int) (*View[T], error) {
+	var zero T
+	t := reflect.TypeOf(&zero).Elem()
+	if err := allocator.CheckValueType(t); err != nil {
+		return nil, errors.Wrapf(err, "type %v can't be placed into a memory region", t)
+	}
+	elemSize := int(t.Size())
+	if elemSize == 0 {
+		return nil, errors.Errorf("type %v has zero size", t)
+	}
+	if off < 0 || length <= 0 || off > region.Size() || length > (region.Size()-off)/elemSize {
+		return nil, errors.Errorf("%d values of type %v at offset %d don't fit into a %d bytes long region", length, t, off, region.Size())
+	}
+	if addr := uintptr(allocator.ByteSliceData(region.Data())) + uintptr(off); addr%uintptr(t.Align()) != 0 {
+		return nil, errors.Errorf("offset %d is not aligned for type %v", off, t)
+	}
+	return &View[T]{region: region, off: off, length: length}, nil
+}
+
+// Len returns the number of values in the view.
+func (v *View[T]) Len() int {
+	return v.length
+}
+
+// Region returns the region of the view.
+func (v *View[T]) Region() *MemoryRegion {
+	return v.region
+}
+
+// Load returns a copy of the first value.
+func (v *View[T]) Load() T {
+	return v.At(0)
+}
+
+// Store sets the first value.
+func (v *View[T]) Store(value T) {
+	v.Set(0, value)
+}
+
+// At returns a copy of the i-th value.
+func (v *View[T]) At(i int) T {
+	result := *v.Ptr(i)
+	allocator.Use(unsafe.Pointer(v.region))
+	return result
+}
+
+// Set sets the i-th value.
+func (v *View[T]) Set(i int, value T) {
+	*v.Ptr(i) = value
+	allocator.Use(unsafe.Pointer(v.region))
+}
+
+// Ptr returns a pointer to the i-th value. It panics, if the index is out of range.
+// Like region's Data(), the pointer must not be used, when the view is not referenced anymore,
+// or after the region was resized.
+func (v *View[T]) Ptr(i int) *T {
+	if i < 0 || i >= v.length {
+		panic(errors.Errorf("index %d is out of range [0, %d)", i, v.length))
+	}
+	var zero T
+	shift := uintptr(v.off) + uintptr(i)*unsafe.Sizeof(zero)
+	data := v.region.Data()
+	if shift+unsafe.Sizeof(zero) > uintptr(len(data)) {
+		panic(errors.Errorf("value %d is beyond the end of the region", i))
+	}
+	return (*T)(allocator.AdvancePointer(allocator.ByteSliceData(data), shift))
+}
+
+// Slice returns the values as a slice.
+// Like region's Data(), the slice must not be used, when the view is not referenced anymore,
+// or after the region was resized.
+func (v *View[T]) Slice() []T {
+	return unsafe.Slice(v.Ptr(0), v.length)
+}
@@ -0,0 +1,81 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build go1.18
+
+package mmf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testViewHeader struct {
+	Magic   uint32
+	Version uint16
+	Flags   uint16
+	Counts  [4]int64
+}
+
+func TestView(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup := newTestRegion(t, 4096)
+	defer cleanup()
+	hdr, err := NewView[testViewHeader](region, 0)
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(1, hdr.Len())
+	hdr.Store(testViewHeader{Magic: 0xcafe, Version: 2, Counts: [4]int64{1, 2, 3, 4}})
+	hdr.Ptr(0).Flags = 7
+	value := hdr.Load()
+	a.Equal(uint32(0xcafe), value.Magic)
+	a.Equal(uint16(7), value.Flags)
+	a.Equal(int64(3), value.Counts[2])
+	another, err := NewView[uint32](region, 0)
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(uint32(0xcafe), another.Load())
+}
+
+func TestArrayView(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup := newTestRegion(t, 4096)
+	defer cleanup()
+	arr, err := NewArrayView[int64](region, 64, 16)
+	if !a.NoError(err) {
+		return
+	}
+	for i := 0; i < arr.Len(); i++ {
+		arr.Set(i, int64(i*i))
+	}
+	a.Equal(int64(81), arr.At(9))
+	s := arr.Slice()
+	a.Len(s, 16)
+	a.Equal(int64(225), s[15])
+	a.Panics(func() { arr.At(16) })
+	a.Panics(func() { arr.Set(-1, 0) })
+}
+
+func TestViewErrors(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup := newTestRegion(t, 4096)
+	defer cleanup()
+	_, err := NewView[int32](region, 2)
+	a.Error(err)
+	_, err = NewView[int64](region, 4092)
+	a.Error(err)
+	_, err = NewArrayView[int32](region, 0, 1025)
+	a.Error(err)
+	_, err = NewArrayView[int32](region, 0, 0)
+	a.Error(err)
+	_, err = NewView[*int](region, 0)
+	a.Error(err)
+	_, err = NewView[string](region, 0)
+	a.Error(err)
+	_, err = NewView[struct{ s []int }](region, 0)
+	a.Error(err)
+	_, err = NewView[struct{}](region, 0)
+	a.Error(err)
+}
@@ -11,7 +11,7 @@ import (
 	"time"
 
 	"github.com/nxgtw/go-ipc/internal/test"
-	"bitbucket.org/avd/go-ipc/mq"
+	"github.com/nxgtw/go-ipc/mq"
 )
 
 var (
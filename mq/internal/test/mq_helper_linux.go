@@ -7,7 +7,7 @@ import (
 	"os"
 	"time"
 
-	"bitbucket.org/avd/go-ipc/mq"
+	"github.com/nxgtw/go-ipc/mq"
 )
 
 func createMqWithType(name string, perm os.FileMode, typ, opt string) (mq.Messenger, error) {
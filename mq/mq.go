@@ -13,6 +13,12 @@ import (
 const (
 	// O_NONBLOCK flag makes mq send/receive operations non-blocking.
 	O_NONBLOCK = common.O_NONBLOCK
+	// O_ATTACH flag makes FastMq register the process in the attach counter of the queue on open,
+	// and unregister it on Close. Processes, which exited without closing the queue, are not counted.
+	O_ATTACH = common.O_ATTACH
+	// O_AUTODESTROY flag is the same as O_ATTACH, and, additionally, makes FastMq destroy itself,
+	// when the last attached process closes it.
+	O_AUTODESTROY = common.O_AUTODESTROY
 )
 
 // Blocker is an object, which can work in blocking and non-blocking modes.
@@ -7,11 +7,12 @@ import (
 	"runtime"
 	"time"
 
+	"github.com/nxgtw/go-ipc/internal/attach"
 	"github.com/nxgtw/go-ipc/internal/common"
 	"github.com/nxgtw/go-ipc/internal/helper"
-	"bitbucket.org/avd/go-ipc/mmf"
-	"bitbucket.org/avd/go-ipc/shm"
-	ipc_sync "bitbucket.org/avd/go-ipc/sync"
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
+	ipc_sync "github.com/nxgtw/go-ipc/sync"
 
 	"github.com/pkg/errors"
 )
@@ -47,6 +48,7 @@ type FastMq struct {
 	impl     *fastMq
 	condSend *ipc_sync.Cond
 	condRecv *ipc_sync.Cond
+	attach   *attach.Attachment
 }
 
 func openFastMq(name string, flag int, perm os.FileMode, maxQueueSize, maxMsgSize int) (*FastMq, error) {
@@ -61,8 +63,14 @@ func openFastMq(name string, flag int, perm os.FileMode, maxQueueSize, maxMsgSiz
 		return nil, errors.Wrap(err, "mq size check failed")
 	}
 
+	// the process is attached before the queue is opened, so that it can't be destroyed in meanwhile.
+	att, err := attach.Open(fastMqAttachName(name), flag, perm)
+	if err != nil {
+		return nil, err
+	}
 	region, created, err := helper.CreateWritableRegion(fastMqStateName(name), openFlags, perm, size)
 	if err != nil {
+		att.Cancel()
 		return nil, errors.Wrap(err, "failed to create shared state")
 	}
 
@@ -70,6 +78,7 @@ func openFastMq(name string, flag int, perm os.FileMode, maxQueueSize, maxMsgSiz
 		region: region,
 		name:   name,
 		flag:   flag,
+		attach: att,
 	}
 	defer func() {
 		fastMqCleanup(result, created, err)
@@ -101,7 +110,7 @@ func openFastMq(name string, flag int, perm os.FileMode, maxQueueSize, maxMsgSiz
 
 // CreateFastMq creates new FastMq.
 //	name - mq name. implementation will create a shm object with this name.
-//	flag - flag is a combination of os.O_EXCL, and O_NONBLOCK.
+//	flag - flag is a combination of os.O_EXCL, O_NONBLOCK, O_ATTACH, and O_AUTODESTROY.
 //	perm - object's permission bits.
 //	maxQueueSize - queue capacity.
 //	maxMsgSize - maximum message size.
@@ -111,17 +120,27 @@ func CreateFastMq(name string, flag int, perm os.FileMode, maxQueueSize, maxMsgS
 
 // OpenFastMq opens an existing message queue. It returns an error, if it does not exist.
 //	name - unique mq name.
-//	flag - 0 or O_NONBLOCK.
+//	flag - 0 or a combination of O_NONBLOCK, O_ATTACH, and O_AUTODESTROY.
 func OpenFastMq(name string, flag int) (*FastMq, error) {
 	maxQueueSize, maxMsgSize, err := FastMqAttrs(name)
 	if err != nil {
 		return nil, err
 	}
-	return openFastMq(name, flag&O_NONBLOCK, 0666, maxQueueSize, maxMsgSize)
+	return openFastMq(name, flag&(O_NONBLOCK|O_ATTACH|O_AUTODESTROY), 0666, maxQueueSize, maxMsgSize)
 }
 
 // DestroyFastMq permanently removes a FastMq.
 func DestroyFastMq(name string) error {
+	errQueue, errAttach := destroyFastMq(name), attach.Destroy(fastMqAttachName(name))
+	if errQueue != nil {
+		return errQueue
+	}
+	return errAttach
+}
+
+// destroyFastMq removes the objects of the queue except its attach table,
+// which is removed by the last attached process itself.
+func destroyFastMq(name string) error {
 	errMutex := ipc_sync.DestroyMutex(fastMqLockerName(name))
 	errObject := shm.DestroyMemoryObject(fastMqStateName(name))
 	errCondSndDestroy := ipc_sync.DestroyCond(fastMqCondName(name, "s"))
@@ -259,6 +278,22 @@ func (mq *FastMq) ReceivePriorityTimeout(data []byte, timeout time.Duration) (in
 	return len, prio, err
 }
 
+// Peek copies the message with the highest priority into data without removing it from the queue.
+// It returns message len and priority. It does not block, and returns an error, if the queue is empty.
+func (mq *FastMq) Peek(data []byte) (int, int, error) {
+	mq.locker.Lock()
+	defer mq.locker.Unlock()
+	if mq.Empty() {
+		return 0, 0, mqEmptyError
+	}
+	return mq.impl.heap.peekMessage(data)
+}
+
+// Len returns the number of messages in the queue.
+func (mq *FastMq) Len() int {
+	return mq.impl.heap.safeLen()
+}
+
 // Cap returns size of the mq buffer.
 func (mq *FastMq) Cap() int {
 	return mq.impl.heap.maxSize()
@@ -275,22 +310,35 @@ func (mq *FastMq) SetBlocking(block bool) error {
 	return nil
 }
 
+// Attached returns the number of alive processes, which opened the queue with O_ATTACH or O_AUTODESTROY.
+// It returns an error, if the queue was opened without these flags.
+func (mq *FastMq) Attached() (int, error) {
+	return mq.attach.Count()
+}
+
 // Close closes a FastMq instance.
+// If the queue was opened with O_AUTODESTROY, and it was the last attached process, the queue is destroyed.
 func (mq *FastMq) Close() error {
 	errLocker := mq.locker.Close()
-	if errRegion := mq.region.Close(); errRegion != nil {
+	errRegion := mq.region.Close()
+	errCondSend, errCondRecv := mq.condSend.Close(), mq.condRecv.Close()
+	name := mq.name
+	errAttach := mq.attach.Close(func() error {
+		return destroyFastMq(name)
+	})
+	if errRegion != nil {
 		return errors.Wrap(errRegion, "failed to close memory region")
 	}
 	if errLocker != nil {
 		return errors.Wrap(errLocker, "failed to close ipc locker")
 	}
-	if err := mq.condSend.Close(); err != nil {
-		return errors.Wrap(err, "failed to close send cond")
+	if errCondSend != nil {
+		return errors.Wrap(errCondSend, "failed to close send cond")
 	}
-	if err := mq.condRecv.Close(); err != nil {
-		return errors.Wrap(err, "failed to close recv cond")
+	if errCondRecv != nil {
+		return errors.Wrap(errCondRecv, "failed to close recv cond")
 	}
-	return nil
+	return errAttach
 }
 
 // Destroy permanently removes a FastMq instance.
@@ -325,24 +373,11 @@ func (mq *FastMq) doReceiveWait(timeout time.Duration) bool {
 	}
 	mq.locker.Lock()
 	mq.impl.header.blockedReceivers++
-	var empty bool
-	common.CallTimeout(func(timeout time.Duration) bool {
-		if empty = mq.Empty(); !empty {
-			return false
-		}
-		if timeout >= 0 {
-			if !mq.condRecv.WaitTimeout(timeout) {
-				return false
-			}
-		} else {
-			mq.condRecv.Wait()
-		}
-		// if the queue is still empty, this was a spurious wakeup, and we can continue waiting.
-		empty = mq.Empty()
-		return empty
+	ok := mq.condRecv.WaitForTimeout(func() bool {
+		return !mq.Empty()
 	}, timeout)
 	mq.impl.header.blockedReceivers--
-	return !empty
+	return ok
 }
 
 func (mq *FastMq) doSendWait(timeout time.Duration) bool {
@@ -355,24 +390,11 @@ func (mq *FastMq) doSendWait(timeout time.Duration) bool {
 	}
 	mq.locker.Lock()
 	mq.impl.header.blockedSenders++
-	var full bool
-	common.CallTimeout(func(timeout time.Duration) bool {
-		if full = mq.Full(); !full {
-			return false
-		}
-		if timeout >= 0 {
-			if !mq.condSend.WaitTimeout(timeout) {
-				return false
-			}
-		} else {
-			mq.condSend.Wait()
-		}
-		// if the queue is still full, this was a spurious wakeup, and we can continue waiting.
-		full = mq.Full()
-		return full
+	ok := mq.condSend.WaitForTimeout(func() bool {
+		return !mq.Full()
 	}, timeout)
 	mq.impl.header.blockedSenders--
-	return !full
+	return ok
 }
 
 func fastMqStateName(mqName string) string {
@@ -387,6 +409,10 @@ func fastMqCondName(mqName, typ string) string {
 	return mqName + ".cv" + typ
 }
 
+func fastMqAttachName(mqName string) string {
+	return mqName + ".atmq"
+}
+
 func fastMqCleanup(mq *FastMq, created bool, err error) {
 	if err == nil {
 		return
@@ -415,4 +441,5 @@ func fastMqCleanup(mq *FastMq, created bool, err error) {
 	if created {
 		shm.DestroyMemoryObject(fastMqStateName(mq.name))
 	}
+	mq.attach.Cancel()
 }
@@ -5,6 +5,10 @@ package mq
 import (
 	"os"
 	"testing"
+
+	"github.com/nxgtw/go-ipc/shm"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func fastMqCtor(name string, flag int, perm os.FileMode) (Messenger, error) {
@@ -88,3 +92,86 @@ func BenchmarkFastMqBlock(b *testing.B) {
 	params := &prioBenchmarkParams{readers: 4, writers: 4, mqSize: 8, msgSize: 1024, flag: 0}
 	benchmarkPrioMq1(b, fastMqCtorPrio, fastMqOpenerPrio, fastMqDtor, params)
 }
+
+// fast-mq-specific tests
+
+func TestFastMqPeek(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyFastMq(testMqName)) {
+		return
+	}
+	mq, err := CreateFastMq(testMqName, os.O_EXCL, 0666, 4, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	data := make([]byte, 16)
+	_, _, err = mq.Peek(data)
+	a.True(IsTemporary(err))
+	a.NoError(mq.SendPriority([]byte{1}, 1))
+	a.NoError(mq.SendPriority([]byte{2, 2}, 3))
+	a.Equal(2, mq.Len())
+	l, prio, err := mq.Peek(data)
+	a.NoError(err)
+	a.Equal(2, l)
+	a.Equal(3, prio)
+	a.Equal([]byte{2, 2}, data[:l])
+	a.Equal(2, mq.Len())
+	_, _, err = mq.Peek(make([]byte, 1))
+	a.Error(err)
+	l, prio, err = mq.ReceivePriority(data)
+	a.NoError(err)
+	a.Equal(2, l)
+	a.Equal(3, prio)
+	a.Equal(1, mq.Len())
+}
+
+func TestFastMqAutoDestroy(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyFastMq(testMqName)) {
+		return
+	}
+	mq, err := CreateFastMq(testMqName, os.O_EXCL|O_AUTODESTROY, 0666, 1, 8)
+	if !a.NoError(err) {
+		return
+	}
+	defer DestroyFastMq(testMqName)
+	mq2, err := OpenFastMq(testMqName, O_AUTODESTROY)
+	if !a.NoError(err) {
+		mq.Close()
+		return
+	}
+	count, err := mq2.Attached()
+	a.NoError(err)
+	a.Equal(2, count)
+	a.NoError(mq.Close())
+	a.NoError(mq2.Send([]byte{1}))
+	a.NoError(mq2.Close())
+	_, err = OpenFastMq(testMqName, 0)
+	a.Error(err)
+}
+
+func TestFastMqNamespace(t *testing.T) {
+	a := assert.New(t)
+	namespaces := []string{"fmq-ns1", "fmq-ns2"}
+	defer shm.SetNamespace("")
+	var queues []*FastMq
+	for _, ns := range namespaces {
+		if !a.NoError(shm.SetNamespace(ns)) {
+			return
+		}
+		a.NoError(DestroyFastMq(testMqName))
+		mq, err := CreateFastMq(testMqName, os.O_EXCL, 0666, 1, 8)
+		if !a.NoError(err) {
+			return
+		}
+		queues = append(queues, mq)
+	}
+	a.NoError(queues[0].Send([]byte{1}))
+	a.Equal(1, queues[0].Len())
+	a.Equal(0, queues[1].Len())
+	for i, mq := range queues {
+		a.NoError(shm.SetNamespace(namespaces[i]))
+		a.NoError(mq.Destroy())
+	}
+}
@@ -65,7 +65,7 @@ func CreateLinuxMessageQueue(name string, flag int, perm os.FileMode, maxQueueSi
 		sysflags |= unix.O_EXCL
 	}
 	attrs := &linuxMqAttr{Maxmsg: maxQueueSize, Msgsize: maxMsgSize}
-	id, err := mq_open(name, sysflags, uint32(perm), attrs)
+	id, err := mq_open(common.Namespaced(name), sysflags, uint32(perm), attrs)
 	if err != nil {
 		return nil, errors.Wrap(err, "mq_open failed")
 	}
@@ -88,19 +88,32 @@ func CreateLinuxMessageQueue(name string, flag int, perm os.FileMode, maxQueueSi
 //		O_RDWR
 //			Open the queue to both send and receive messages.
 func OpenLinuxMessageQueue(name string, flag int) (*LinuxMessageQueue, error) {
-	id, err := mq_open(name, common.FlagsForAccess(flag)|unix.O_CLOEXEC, uint32(0), nil)
+	id, err := mq_open(common.Namespaced(name), common.FlagsForAccess(flag)|unix.O_CLOEXEC, uint32(0), nil)
 	if err != nil {
 		return nil, errors.Wrap(err, "mq_open failed")
 	}
+	result, err := NewLinuxMessageQueueFromFd(id, name, flag)
+	if err != nil {
+		unix.Close(id)
+		return nil, err
+	}
+	return result, nil
+}
+
+// NewLinuxMessageQueueFromFd returns a queue for the given mq descriptor,
+// for example, for the one, which was received from another process.
+// The queue takes the ownership of the descriptor.
+//	name - the name of the queue. It is used by Destroy only and may be empty.
+//	flag - 0 or O_NONBLOCK. Access mode is defined by the descriptor.
+func NewLinuxMessageQueueFromFd(fd int, name string, flag int) (*LinuxMessageQueue, error) {
 	result := &LinuxMessageQueue{
-		id:           id,
+		id:           fd,
 		name:         name,
 		cancelSocket: -1,
 		flags:        flag,
 	}
 	attrs, err := result.getAttrs()
 	if err != nil {
-		result.Close()
 		return nil, errors.Wrap(err, "failed to get mq attrs")
 	}
 	result.inputBuff = make([]byte, attrs.Msgsize)
@@ -211,6 +224,11 @@ func (mq *LinuxMessageQueue) ID() int {
 	return mq.id
 }
 
+// Name returns the name of the queue.
+func (mq *LinuxMessageQueue) Name() string {
+	return mq.name
+}
+
 // Close closes the queue.
 func (mq *LinuxMessageQueue) Close() error {
 	if mq.cancelSocket >= 0 {
@@ -232,6 +250,15 @@ func (mq *LinuxMessageQueue) Cap() int {
 	return attrs.Maxmsg
 }
 
+// Len returns the number of messages in the queue.
+func (mq *LinuxMessageQueue) Len() int {
+	attrs, err := mq.getAttrs()
+	if err != nil {
+		return 0
+	}
+	return attrs.Curmsgs
+}
+
 // SetBlocking sets whether the send/receive operations on the queue block.
 // This applies to the current instance only.
 func (mq *LinuxMessageQueue) SetBlocking(block bool) error {
@@ -308,7 +335,7 @@ func (mq *LinuxMessageQueue) getAttrs() (*linuxMqAttr, error) {
 
 // DestroyLinuxMessageQueue removes the queue permanently.
 func DestroyLinuxMessageQueue(name string) error {
-	err := mq_unlink(name)
+	err := mq_unlink(common.Namespaced(name))
 	if err != nil {
 		if os.IsNotExist(err) {
 			err = nil
@@ -10,7 +10,11 @@ import (
 	"time"
 
 	"github.com/nxgtw/go-ipc/internal/test"
+	"github.com/nxgtw/go-ipc/shm"
+
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
 )
 
 func linuxMqCtor(name string, flag int, perm os.FileMode) (Messenger, error) {
@@ -98,6 +102,39 @@ func TestLinuxMqGetAttrs(t *testing.T) {
 	assert.Equal(t, 5, attrs.Maxmsg)
 	assert.Equal(t, 121, attrs.Msgsize)
 	assert.Equal(t, 1, attrs.Curmsgs)
+	assert.Equal(t, 1, mq.Len())
+}
+
+func TestLinuxMqFromFd(t *testing.T) {
+	if !assert.NoError(t, DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 5, 121)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer mq.Destroy()
+	fd, err := unix.Dup(mq.ID())
+	if !assert.NoError(t, err) {
+		return
+	}
+	mq2, err := NewLinuxMessageQueueFromFd(fd, testMqName, O_NONBLOCK)
+	if !assert.NoError(t, err) {
+		unix.Close(fd)
+		return
+	}
+	defer mq2.Close()
+	assert.Equal(t, testMqName, mq2.Name())
+	assert.Equal(t, 5, mq2.Cap())
+	assert.NoError(t, mq.Send([]byte{1, 2, 3}))
+	data := make([]byte, 121)
+	l, err := mq2.Receive(data)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, data[:l])
+	_, err = mq2.Receive(data)
+	assert.True(t, IsTemporary(errors.Cause(err)))
+	_, err = NewLinuxMessageQueueFromFd(-1, "", 0)
+	assert.Error(t, err)
 }
 
 func TestLinuxMqNotifyOnce(t *testing.T) {
@@ -191,3 +228,27 @@ func BenchmarkLinuxMqBlock(b *testing.B) {
 	params := &prioBenchmarkParams{readers: 4, writers: 4, mqSize: 8, msgSize: 1024, flag: 0}
 	benchmarkPrioMq1(b, linuxMqCtorPrio, linuxMqOpenerPrio, linuxMqDtor, params)
 }
+
+func TestLinuxMqNamespace(t *testing.T) {
+	a := assert.New(t)
+	defer shm.SetNamespace("")
+	var queues []*LinuxMessageQueue
+	for _, ns := range []string{"mq-ns1", "mq-ns2"} {
+		if !a.NoError(shm.SetNamespace(ns)) {
+			return
+		}
+		a.NoError(DestroyLinuxMessageQueue(testMqName))
+		mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL, 0666, 1, 8)
+		if !a.NoError(err) {
+			return
+		}
+		queues = append(queues, mq)
+	}
+	a.NoError(queues[0].Send([]byte{1}))
+	a.Equal(1, queues[0].Len())
+	a.Equal(0, queues[1].Len())
+	for i, mq := range queues {
+		a.NoError(shm.SetNamespace([]string{"mq-ns1", "mq-ns2"}[i]))
+		a.NoError(mq.Destroy())
+	}
+}
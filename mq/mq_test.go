@@ -15,7 +15,7 @@ import (
 	"github.com/nxgtw/go-ipc/internal/allocator"
 	"github.com/nxgtw/go-ipc/internal/common"
 	testutil "github.com/nxgtw/go-ipc/internal/test"
-	ipc_sync "bitbucket.org/avd/go-ipc/sync"
+	ipc_sync "github.com/nxgtw/go-ipc/sync"
 
 	"github.com/stretchr/testify/assert"
 )
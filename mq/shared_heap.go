@@ -60,6 +60,15 @@ func (mq *sharedHeap) popMessage(data []byte) (int, int, error) {
 	return len(msg.data), int(msg.prio), nil
 }
 
+func (mq *sharedHeap) peekMessage(data []byte) (int, int, error) {
+	msg := mq.at(0)
+	if len(msg.data) > len(data) {
+		return 0, 0, errors.New("the message is too long")
+	}
+	copy(data, msg.data)
+	return len(msg.data), int(msg.prio), nil
+}
+
 func (mq *sharedHeap) safeLen() int {
 	return mq.array.SafeLen()
 }
@@ -9,7 +9,7 @@ import (
 	"strconv"
 
 	"github.com/nxgtw/go-ipc/internal/test"
-	"bitbucket.org/avd/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/mmf"
 )
 
 var (
@@ -7,7 +7,7 @@ import (
 	"runtime"
 
 	"github.com/nxgtw/go-ipc/internal/common"
-	"bitbucket.org/avd/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/mmf"
 	"github.com/pkg/errors"
 )
 
@@ -56,7 +56,7 @@ func NewMemoryObjectSize(name string, flag int, perm os.FileMode, size int64) (S
 	var obj *MemoryObject
 	creator := func(create bool) error {
 		var err error
-		creatorFlag := os.O_RDWR
+		creatorFlag := os.O_RDWR | flag&common.O_HUGETLB
 		if create {
 			creatorFlag |= (os.O_CREATE | os.O_EXCL)
 		}
@@ -113,6 +113,21 @@ func (obj *MemoryObject) Fd() uintptr {
 	return obj.memoryObject.Fd()
 }
 
+// SetNamespace sets the namespace for all named objects of shm, mq, and sync packages.
+// The namespace is added to the names of the objects in the os, so that objects with the same name
+// in different namespaces do not collide. It should be set before any object is opened,
+// and all the processes, which share objects, must use the same namespace.
+// The initial namespace is taken from GO_IPC_NAMESPACE environment variable.
+//	ns - latin letters, digits, '-', and '_'. An empty namespace disables namespacing.
+func SetNamespace(ns string) error {
+	return common.SetNamespace(ns)
+}
+
+// Namespace returns current namespace.
+func Namespace() string {
+	return common.Namespace()
+}
+
 // DestroyMemoryObject permanently removes given memory object.
 func DestroyMemoryObject(name string) error {
 	return destroyMemoryObject(name)
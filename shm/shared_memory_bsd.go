@@ -11,7 +11,9 @@ import (
 	"unsafe"
 
 	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/common"
 
+	"github.com/pkg/errors"
 	"golang.org/x/sys/unix"
 )
 
@@ -25,8 +27,17 @@ func doDestroyMemoryObject(path string) error {
 	return err
 }
 
+func hugeShmName(name string) (string, error) {
+	return "", errors.New("huge pages are not supported")
+}
+
+func checkHugePages() error {
+	return nil
+}
+
 func shmName(name string) (string, error) {
 	const maxNameLen = 30
+	name = common.Namespaced(name)
 	// workaround from http://www.opensource.apple.com/source/Libc/Libc-320/sys/shm_open.c
 	if isDarwin {
 		newName := fmt.Sprintf("%s\t%d", name, unix.Geteuid())
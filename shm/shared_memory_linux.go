@@ -7,25 +7,41 @@ package shm
 import (
 	"bufio"
 	"io"
+	"io/ioutil"
 	"os"
 	"strconv"
 	"strings"
 	"sync"
 
+	"github.com/nxgtw/go-ipc/internal/common"
+
 	"github.com/pkg/errors"
 	"golang.org/x/sys/unix"
 )
 
+const (
+	// O_HUGETLB flag makes NewMemoryObject place the object on a hugetlbfs mount,
+	// so that it is backed by huge pages. The size of such objects is rounded up to the huge page size.
+	// All the processes must open the object with this flag.
+	O_HUGETLB = common.O_HUGETLB
+	// DirectoryEnv is an environment variable, which overrides the directory for shared memory objects.
+	DirectoryEnv = "GO_IPC_SHM_DIR"
+)
+
 const (
 	maxNameLen       = 255
 	defaultShmPath   = "/dev/shm/"
+	defaultHugePath  = "/dev/hugepages/"
 	cShmfsSuperMagic = 0x01021994
 	cRamfsMagic      = 0x858458f6
 )
 
 var (
-	shmPathOnce sync.Once
-	shmPath     string
+	shmPathOnce  sync.Once
+	shmPathMu    sync.RWMutex
+	shmPath      string
+	hugePathOnce sync.Once
+	hugePath     string
 )
 
 type mntent struct {
@@ -45,6 +61,48 @@ func doDestroyMemoryObject(path string) error {
 	return err
 }
 
+func destroyHugeMemoryObject(name string) error {
+	if _, err := hugeDirectory(); err != nil {
+		return nil
+	}
+	path, err := hugeShmName(name)
+	if err != nil {
+		return errors.Wrap(err, "shm name failed")
+	}
+	if err = doDestroyMemoryObject(path); err != nil {
+		err = errors.Wrapf(err, "failed to destroy shm object %q", path)
+	}
+	return err
+}
+
+// checkHugePages returns an error, if the system has no huge pages, which can be allocated.
+// if it can't find out, it returns nil, leaving the error to mmap.
+func checkHugePages() error {
+	meminfo, err := ioutil.ReadFile("/proc/meminfo")
+	if err != nil {
+		return nil
+	}
+	for _, line := range strings.Split(string(meminfo), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "HugePages_Total:" {
+			continue
+		}
+		if total, err := strconv.Atoi(fields[1]); err != nil || total > 0 {
+			return nil
+		}
+		// huge pages may be allocated on demand.
+		overcommit, err := ioutil.ReadFile("/proc/sys/vm/nr_overcommit_hugepages")
+		if err != nil {
+			return nil
+		}
+		if count, err := strconv.Atoi(strings.TrimSpace(string(overcommit))); err != nil || count > 0 {
+			return nil
+		}
+		return errors.New("no huge pages are reserved, see /proc/sys/vm/nr_hugepages")
+	}
+	return nil
+}
+
 // glibc/sysdeps/posix/shm_open.c
 func shmOpen(path string, flag int, perm os.FileMode) (*os.File, error) {
 	return os.OpenFile(path, flag, perm)
@@ -52,47 +110,120 @@ func shmOpen(path string, flag int, perm os.FileMode) (*os.File, error) {
 
 // glibc/sysdeps/posix/shm-directory.h
 func shmName(name string) (string, error) {
-	name = strings.TrimLeft(name, "/")
+	return objectPath(name, shmDirectory)
+}
+
+func hugeShmName(name string) (string, error) {
+	return objectPath(name, hugeDirectory)
+}
+
+func objectPath(name string, directory func() (string, error)) (string, error) {
+	name = common.Namespaced(strings.TrimLeft(name, "/"))
 	nameLen := len(name)
 	if nameLen == 0 || nameLen >= maxNameLen || strings.Contains(name, "/") {
 		return "", errors.New("invalid shm name")
 	}
 	var dir string
 	var err error
-	if dir, err = shmDirectory(); err != nil {
+	if dir, err = directory(); err != nil {
 		return "", errors.Wrap(err, "error building shared memory name")
 	}
 	return dir + name, nil
 }
 
+// Directory returns the directory, where shared memory objects are stored.
+func Directory() (string, error) {
+	return shmDirectory()
+}
+
+// SetDirectory sets the directory, where shared memory objects are created.
+// It overrides the directory from DirectoryEnv variable and the detected shmfs mount.
+// The directory should be on tmpfs, otherwise the objects are stored on disk.
+// All the processes, which share the objects, must use the same directory.
+func SetDirectory(dir string) error {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return errors.Wrap(err, "failed to access shm directory")
+	}
+	if !fi.IsDir() {
+		return errors.Errorf("%q is not a directory", dir)
+	}
+	// prevent the detection from overwriting the directory.
+	shmPathOnce.Do(func() {})
+	shmPathMu.Lock()
+	shmPath = withTrailingSlash(dir)
+	shmPathMu.Unlock()
+	return nil
+}
+
 func shmDirectory() (string, error) {
 	shmPathOnce.Do(locateShmFs)
+	shmPathMu.RLock()
+	defer shmPathMu.RUnlock()
 	if len(shmPath) == 0 {
 		return shmPath, errors.New("error locating the shared memory path")
 	}
 	return shmPath, nil
 }
 
+// DestroyHugeMemoryObject permanently removes given memory object, which was created with O_HUGETLB.
+func DestroyHugeMemoryObject(name string) error {
+	return destroyHugeMemoryObject(name)
+}
+
+// HugePageDirectory returns the directory, where memory objects opened with O_HUGETLB are stored.
+func HugePageDirectory() (string, error) {
+	return hugeDirectory()
+}
+
+func hugeDirectory() (string, error) {
+	hugePathOnce.Do(locateHugeFs)
+	if len(hugePath) == 0 {
+		return hugePath, errors.New("hugetlbfs is not mounted")
+	}
+	return hugePath, nil
+}
+
 // glibc/sysdeps/unix/sysv/linux/shm-directory.c
 func locateShmFs() {
-	if checkShmPath(defaultShmPath) {
+	if dir := os.Getenv(DirectoryEnv); len(dir) > 0 {
+		shmPath = withTrailingSlash(dir)
+	} else if checkShmPath(defaultShmPath) {
 		shmPath = defaultShmPath
 	} else {
 		shmPath = shmFsFromMounts()
 	}
 }
 
+func locateHugeFs() {
+	if checkHugePath(defaultHugePath) {
+		hugePath = defaultHugePath
+	} else {
+		hugePath = fsFromMounts(hugeFsFromReader)
+	}
+}
+
 func checkShmPath(path string) bool {
+	fsType, ok := fsTypeOf(path)
+	return ok && isShmFs(fsType)
+}
+
+func checkHugePath(path string) bool {
+	fsType, ok := fsTypeOf(path)
+	return ok && fsType == common.HugetlbfsMagic
+}
+
+func fsTypeOf(path string) (int64, bool) {
 	if len(path) == 0 {
-		return false
+		return 0, false
 	}
 	var statfs unix.Statfs_t
 	if err := unix.Statfs(path, &statfs); err != nil {
-		return false
+		return 0, false
 	}
 	// unconvert says 'warning: redundant type conversion',
 	// however, it is not, as statfs.Type has different types on different platforms.
-	return isShmFs(int64(statfs.Type))
+	return int64(statfs.Type), true
 }
 
 func isShmFs(fsType int64) bool {
@@ -100,6 +231,10 @@ func isShmFs(fsType int64) bool {
 }
 
 func shmFsFromMounts() string {
+	return fsFromMounts(shmFsFromReader)
+}
+
+func fsFromMounts(fromReader func(io.Reader) string) string {
 	var fsFile *os.File
 	var err error
 	if fsFile, err = os.Open("/proc/mounts"); err != nil {
@@ -107,28 +242,43 @@ func shmFsFromMounts() string {
 			return ""
 		}
 	}
-	return shmFsFromReader(fsFile)
+	defer fsFile.Close()
+	return fromReader(fsFile)
 }
 
 func shmFsFromReader(r io.Reader) string {
+	return fsFromReader(r, func(record *mntent) bool {
+		return (record.fstype == "tmpfs" || record.fstype == "shm") && checkShmPath(record.dir)
+	})
+}
+
+func hugeFsFromReader(r io.Reader) string {
+	return fsFromReader(r, func(record *mntent) bool {
+		return record.fstype == "hugetlbfs" && checkHugePath(record.dir)
+	})
+}
+
+// fsFromReader returns the first mount point from fstab-formatted data, which satisfies the filter.
+func fsFromReader(r io.Reader, filter func(record *mntent) bool) string {
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := scanner.Text()
 		if record := scanMountRecord(line); record != nil {
-			if record.fstype == "tmpfs" || record.fstype == "shm" {
-				result := record.dir
-				if checkShmPath(result) {
-					if !strings.HasSuffix(result, "/") {
-						result = result + "/"
-					}
-					return result
-				}
+			if filter(record) {
+				return withTrailingSlash(record.dir)
 			}
 		}
 	}
 	return ""
 }
 
+func withTrailingSlash(dir string) string {
+	if !strings.HasSuffix(dir, "/") {
+		dir = dir + "/"
+	}
+	return dir
+}
+
 func scanMountRecord(record string) (result *mntent) {
 	wordScanner := bufio.NewScanner(strings.NewReader(record))
 	wordScanner.Split(bufio.ScanWords)
@@ -3,8 +3,14 @@
 package shm
 
 import (
+	"io/ioutil"
+	"os"
 	"strings"
 	"testing"
+
+	"github.com/nxgtw/go-ipc/mmf"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestShmFsFromReader(t *testing.T) {
@@ -37,3 +43,109 @@ func TestShmFsFromMountPoints(t *testing.T) {
 		t.Errorf("couldn't find a correct shm path")
 	}
 }
+
+func TestFsFromReader(t *testing.T) {
+	const testData = `
+		tmpfs /dev/shm tmpfs rw,nosuid,nodev 0 0
+		hugetlbfs /mnt/huge hugetlbfs rw,relatime,pagesize=2M 0 0
+	`
+	path := fsFromReader(strings.NewReader(testData), func(record *mntent) bool {
+		return record.fstype == "hugetlbfs"
+	})
+	if path != "/mnt/huge/" {
+		t.Errorf("hugetlbfs mountpoint not parsed. expected '/mnt/huge/', got '%s'", path)
+	}
+}
+
+func TestHugeMemoryObject(t *testing.T) {
+	a := assert.New(t)
+	const name = "shm-huge-test"
+	if _, err := HugePageDirectory(); err != nil || checkHugePages() != nil {
+		_, err = NewMemoryObject(name, os.O_CREATE|os.O_RDWR|O_HUGETLB, 0666)
+		a.Error(err)
+		t.Skip("huge pages are not available")
+	}
+	a.NoError(DestroyHugeMemoryObject(name))
+	obj, err := NewMemoryObject(name, os.O_CREATE|os.O_EXCL|os.O_RDWR|O_HUGETLB, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer obj.Destroy()
+	a.NoError(obj.Truncate(1))
+	pageSize := obj.Size()
+	a.True(pageSize > int64(os.Getpagesize()))
+	region, err := mmf.NewMemoryRegion(obj, mmf.MEM_READWRITE|mmf.MEM_HUGETLB, 0, 1024)
+	if a.NoError(err) {
+		a.Equal(1024, len(region.Data()))
+		copy(region.Data(), shmTestData)
+		a.NoError(region.Close())
+	}
+	_, err = mmf.NewMemoryRegion(obj, mmf.MEM_READWRITE, 1024, 1024)
+	a.Error(err)
+}
+
+func TestNamespace(t *testing.T) {
+	a := assert.New(t)
+	const name = "shm-ns-test"
+	dir, err := Directory()
+	if !a.NoError(err) {
+		return
+	}
+	a.Error(SetNamespace("a/b"))
+	a.Error(SetNamespace("a.b"))
+	defer SetNamespace("")
+	var objects []*MemoryObject
+	for _, ns := range []string{"ns1", "ns2"} {
+		if !a.NoError(SetNamespace(ns)) {
+			return
+		}
+		a.Equal(ns, Namespace())
+		a.NoError(DestroyMemoryObject(name))
+		obj, err := NewMemoryObject(name, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666)
+		if !a.NoError(err) {
+			return
+		}
+		objects = append(objects, obj)
+		_, err = os.Stat(dir + ns + "." + name)
+		a.NoError(err)
+		a.Equal(name, obj.Name())
+	}
+	for i, obj := range objects {
+		a.NoError(SetNamespace([]string{"ns1", "ns2"}[i]))
+		a.NoError(DestroyMemoryObject(name))
+		obj.Close()
+	}
+	_, err = os.Stat(dir + "ns1." + name)
+	a.True(os.IsNotExist(err))
+}
+
+func TestSetDirectory(t *testing.T) {
+	a := assert.New(t)
+	const name = "shm-dir-test"
+	oldDir, err := Directory()
+	if !a.NoError(err) {
+		return
+	}
+	a.Error(SetDirectory(oldDir + "not-exists"))
+	dir, err := ioutil.TempDir(oldDir, "shm-dir")
+	if !a.NoError(err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	if !a.NoError(SetDirectory(dir)) {
+		return
+	}
+	defer SetDirectory(oldDir)
+	newDir, err := Directory()
+	a.NoError(err)
+	a.Equal(dir+"/", newDir)
+	obj, err := NewMemoryObject(name, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	_, err = os.Stat(dir + "/" + name)
+	a.NoError(err)
+	a.NoError(obj.Destroy())
+	_, err = os.Stat(dir + "/" + name)
+	a.True(os.IsNotExist(err))
+}
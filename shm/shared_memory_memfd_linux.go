@@ -0,0 +1,89 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package shm
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// MFD_ALLOW_SEALING flag allows to add seals to an anonymous memory object.
+	MFD_ALLOW_SEALING = unix.MFD_ALLOW_SEALING
+	// MFD_HUGETLB flag makes an anonymous memory object use huge pages of the default size.
+	// The size of such objects is rounded up to the huge page size.
+	MFD_HUGETLB = unix.MFD_HUGETLB
+
+	// F_SEAL_SEAL prevents adding more seals.
+	F_SEAL_SEAL = unix.F_SEAL_SEAL
+	// F_SEAL_SHRINK prevents reducing the size of the object.
+	F_SEAL_SHRINK = unix.F_SEAL_SHRINK
+	// F_SEAL_GROW prevents increasing the size of the object.
+	F_SEAL_GROW = unix.F_SEAL_GROW
+	// F_SEAL_WRITE prevents modifying the contents of the object.
+	F_SEAL_WRITE = unix.F_SEAL_WRITE
+
+	anonymousObjectName = "go-ipc"
+)
+
+// NewAnonymousMemoryObject creates a memory object, which has no name in the file system.
+// It is based on memfd_create, and is removed by the os, when all its descriptors and mappings are closed,
+// so it does not leak, if the process crashes. The object can be passed to child processes
+// with PassMemoryObject, or to other processes over unix sockets.
+//	size - object size.
+func NewAnonymousMemoryObject(size int64) (*MemoryObject, error) {
+	return NewAnonymousMemoryObjectFlags(size, 0)
+}
+
+// NewAnonymousMemoryObjectFlags creates an anonymous memory object with the given flags.
+// See NewAnonymousMemoryObject for details.
+//	size - object size.
+//	flag - 0 or a combination of MFD_ALLOW_SEALING and MFD_HUGETLB.
+func NewAnonymousMemoryObjectFlags(size int64, flag int) (*MemoryObject, error) {
+	if flag&^(MFD_ALLOW_SEALING|MFD_HUGETLB) != 0 {
+		return nil, errors.Errorf("invalid memfd flags %#x", flag)
+	}
+	if flag&MFD_HUGETLB != 0 {
+		if err := checkHugePages(); err != nil {
+			return nil, err
+		}
+	}
+	fd, err := unix.MemfdCreate(anonymousObjectName, flag|unix.MFD_CLOEXEC)
+	if err != nil {
+		return nil, errors.Wrap(os.NewSyscallError("memfd_create", err), "failed to create anonymous memory object")
+	}
+	result := newAnonymousMemoryObject(os.NewFile(uintptr(fd), "memfd:"+anonymousObjectName))
+	if err = result.Truncate(size); err != nil {
+		result.Close()
+		return nil, errors.Wrap(err, "failed to truncate anonymous memory object")
+	}
+	return result, nil
+}
+
+// Seal adds seals to the object. Seals can't be removed, and are applied to all its descriptors,
+// including the ones in other processes. The object must be created with MFD_ALLOW_SEALING.
+// F_SEAL_WRITE fails, if the object has writable shared mappings.
+//	seals - a combination of F_SEAL_SEAL, F_SEAL_SHRINK, F_SEAL_GROW, and F_SEAL_WRITE.
+func (obj *MemoryObject) Seal(seals int) error {
+	if seals&^(F_SEAL_SEAL|F_SEAL_SHRINK|F_SEAL_GROW|F_SEAL_WRITE) != 0 {
+		return errors.Errorf("invalid seals %#x", seals)
+	}
+	if _, err := unix.FcntlInt(obj.Fd(), unix.F_ADD_SEALS, seals); err != nil {
+		if err == unix.EPERM {
+			return errors.Wrap(os.NewSyscallError("fcntl", err), "the object is sealed with F_SEAL_SEAL or does not allow sealing")
+		}
+		return errors.Wrap(os.NewSyscallError("fcntl", err), "failed to add seals")
+	}
+	return nil
+}
+
+// Seals returns the seals of the object.
+func (obj *MemoryObject) Seals() (int, error) {
+	seals, err := unix.FcntlInt(obj.Fd(), unix.F_GET_SEALS, 0)
+	if err != nil {
+		return 0, errors.Wrap(os.NewSyscallError("fcntl", err), "failed to get seals")
+	}
+	return seals, nil
+}
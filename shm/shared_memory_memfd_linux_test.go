@@ -0,0 +1,165 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package shm
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+
+	"github.com/nxgtw/go-ipc/mmf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	anonHelperEnv = "GO_IPC_SHM_ANON_HELPER"
+)
+
+func TestAnonymousMemoryObject(t *testing.T) {
+	a := assert.New(t)
+	obj, err := NewAnonymousMemoryObject(1024)
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(int64(1024), obj.Size())
+	a.Equal("", obj.Name())
+	region, err := mmf.NewMemoryRegion(obj, mmf.MEM_READWRITE, 0, 1024)
+	if a.NoError(err) {
+		copy(region.Data(), shmTestData)
+		a.NoError(region.Close())
+	}
+	a.NoError(obj.Destroy())
+	_, err = NewAnonymousMemoryObjectFlags(1024, 0x1000)
+	a.Error(err)
+}
+
+func TestMemoryObjectFromFdNotRegular(t *testing.T) {
+	a := assert.New(t)
+	r, w, err := os.Pipe()
+	if !a.NoError(err) {
+		return
+	}
+	defer r.Close()
+	defer w.Close()
+	_, err = NewMemoryObjectFromFd(r.Fd())
+	a.Error(err)
+}
+
+func TestAnonymousMemoryObjectInherited(t *testing.T) {
+	a := assert.New(t)
+	obj, err := NewAnonymousMemoryObject(128)
+	if !a.NoError(err) {
+		return
+	}
+	defer obj.Destroy()
+	region, err := mmf.NewMemoryRegion(obj, mmf.MEM_READWRITE, 0, 128)
+	if !a.NoError(err) {
+		return
+	}
+	defer region.Close()
+	copy(region.Data(), shmTestData[:127])
+	cmd := exec.Command(os.Args[0], "-test.run=^TestAnonymousMemoryObjectHelper$")
+	idx := PassMemoryObject(cmd, obj)
+	cmd.Env = append(os.Environ(), anonHelperEnv+"="+strconv.Itoa(idx))
+	output, err := cmd.CombinedOutput()
+	if !a.NoError(err, string(output)) {
+		return
+	}
+	// the helper sets the last byte.
+	a.Equal(byte(0xFF), region.Data()[127])
+}
+
+// TestAnonymousMemoryObjectHelper is run in a child process by TestAnonymousMemoryObjectInherited.
+func TestAnonymousMemoryObjectHelper(t *testing.T) {
+	env := os.Getenv(anonHelperEnv)
+	if len(env) == 0 {
+		t.Skip("not a helper process")
+	}
+	idx, err := strconv.Atoi(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err := InheritedMemoryObject(idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obj.Close()
+	region, err := mmf.NewMemoryRegion(obj, mmf.MEM_READWRITE, 0, int(obj.Size()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer region.Close()
+	data := region.Data()
+	for i := 0; i < len(data)-1; i++ {
+		if data[i] != shmTestData[i] {
+			t.Fatalf("invalid value at %d: %d", i, data[i])
+		}
+	}
+	data[len(data)-1] = 0xFF
+}
+
+func TestAnonymousMemoryObjectSeal(t *testing.T) {
+	a := assert.New(t)
+	obj, err := NewAnonymousMemoryObjectFlags(1024, MFD_ALLOW_SEALING)
+	if !a.NoError(err) {
+		return
+	}
+	defer obj.Destroy()
+	seals, err := obj.Seals()
+	a.NoError(err)
+	a.Equal(0, seals)
+	_, err = mmf.NewMemoryRegion(obj, mmf.MEM_READ_ONLY|mmf.MEM_SEALED, 0, 1024)
+	a.Error(err)
+	if !a.NoError(obj.Seal(F_SEAL_SHRINK|F_SEAL_GROW)) {
+		return
+	}
+	seals, err = obj.Seals()
+	a.NoError(err)
+	a.Equal(F_SEAL_SHRINK|F_SEAL_GROW, seals)
+	a.Error(obj.Truncate(512))
+	a.Error(obj.Truncate(2048))
+	a.Equal(int64(1024), obj.Size())
+	region, err := mmf.NewMemoryRegion(obj, mmf.MEM_READWRITE|mmf.MEM_SEALED, 0, 1024)
+	if a.NoError(err) {
+		a.Error(obj.Seal(F_SEAL_WRITE))
+		a.NoError(region.Close())
+	}
+	a.NoError(obj.Seal(F_SEAL_SEAL))
+	a.Error(obj.Seal(F_SEAL_WRITE))
+	a.Error(obj.Seal(0x1000))
+}
+
+func TestAnonymousMemoryObjectNoSealing(t *testing.T) {
+	a := assert.New(t)
+	obj, err := NewAnonymousMemoryObject(1024)
+	if !a.NoError(err) {
+		return
+	}
+	defer obj.Destroy()
+	seals, err := obj.Seals()
+	a.NoError(err)
+	a.Equal(F_SEAL_SEAL, seals)
+	a.Error(obj.Seal(F_SEAL_SHRINK))
+}
+
+func TestAnonymousMemoryObjectHuge(t *testing.T) {
+	a := assert.New(t)
+	if checkHugePages() != nil {
+		_, err := NewAnonymousMemoryObjectFlags(1024, MFD_HUGETLB)
+		a.Error(err)
+		t.Skip("huge pages are not available")
+	}
+	obj, err := NewAnonymousMemoryObjectFlags(1024, MFD_HUGETLB)
+	if !a.NoError(err) {
+		return
+	}
+	defer obj.Destroy()
+	a.True(obj.Size() > 1024)
+	region, err := mmf.NewMemoryRegion(obj, mmf.MEM_READWRITE|mmf.MEM_HUGETLB, 0, 1024)
+	if a.NoError(err) {
+		copy(region.Data(), shmTestData)
+		a.NoError(region.Close())
+	}
+}
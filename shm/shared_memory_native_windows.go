@@ -48,6 +48,7 @@ func NewWindowsNativeMemoryObject(name string, flag, size int) (*WindowsNativeMe
 	maxSizeLow := uint32((int64(size)) & 0xFFFFFFFF)
 
 	var handle windows.Handle
+	sysName := common.Namespaced(name)
 	creator := func(create bool) error {
 		if create {
 			handle, err = sys.CreateFileMapping(
@@ -56,12 +57,12 @@ func NewWindowsNativeMemoryObject(name string, flag, size int) (*WindowsNativeMe
 				prot,
 				maxSizeHigh,
 				maxSizeLow,
-				name)
+				sysName)
 			if os.IsExist(err) {
 				windows.CloseHandle(handle)
 			}
 		} else {
-			handle, err = sys.OpenFileMapping(sysFlags, 0, name)
+			handle, err = sys.OpenFileMapping(sysFlags, 0, sysName)
 		}
 		return err
 	}
@@ -7,7 +7,7 @@ import (
 	"testing"
 
 	"github.com/nxgtw/go-ipc/internal/test"
-	"bitbucket.org/avd/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/mmf"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -10,7 +10,7 @@ import (
 	"time"
 
 	"github.com/nxgtw/go-ipc/internal/test"
-	"bitbucket.org/avd/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/mmf"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -6,11 +6,16 @@ package shm
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 
+	"github.com/nxgtw/go-ipc/internal/common"
+
 	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
 )
 
 const (
@@ -19,18 +24,38 @@ const (
 
 type memoryObject struct {
 	file *os.File
+	// anonymous objects have no name in the file system.
+	// they are removed by the os, when all their descriptors and mappings are closed.
+	anonymous bool
+	// hugePageSize is not 0 for objects backed by huge pages.
+	hugePageSize int64
 }
 
 func newMemoryObject(name string, flag int, perm os.FileMode) (*memoryObject, error) {
-	path, err := shmName(name)
+	huge := flag&common.O_HUGETLB != 0
+	flag &^= common.O_HUGETLB
+	nameFunc := shmName
+	if huge {
+		nameFunc = hugeShmName
+	}
+	path, err := nameFunc(name)
 	if err != nil {
 		return nil, errors.Wrap(err, "shm name failed")
 	}
+	if huge && flag&os.O_CREATE != 0 {
+		if err = checkHugePages(); err != nil {
+			return nil, err
+		}
+	}
 	file, err := shmOpen(path, flag, perm)
 	if err != nil {
 		return nil, errors.Wrap(err, "shm open failed")
 	}
-	return &memoryObject{file: file}, nil
+	result := &memoryObject{file: file}
+	if huge {
+		result.hugePageSize = common.HugePageSize(file.Fd())
+	}
+	return result, nil
 }
 
 func (obj *memoryObject) Destroy() error {
@@ -39,6 +64,9 @@ func (obj *memoryObject) Destroy() error {
 			return errors.Wrap(err, "close failed")
 		}
 	}
+	if obj.anonymous {
+		return nil
+	}
 	if err := doDestroyMemoryObject(obj.file.Name()); err != nil {
 		return errors.Wrap(err, "unable to destroy memory object")
 	}
@@ -46,12 +74,18 @@ func (obj *memoryObject) Destroy() error {
 }
 
 func (obj *memoryObject) Name() string {
+	if obj.anonymous {
+		return ""
+	}
 	result := filepath.Base(obj.file.Name())
 	// on darwin we do this trick due to
 	// http://www.opensource.apple.com/source/Libc/Libc-320/sys/shm_open.c
 	if isDarwin {
 		result = result[:strings.LastIndex(result, "\t")]
 	}
+	if name, ok := common.StripNamespace(result); ok {
+		result = name
+	}
 	return result
 }
 
@@ -72,6 +106,10 @@ func (obj *memoryObject) Close() error {
 }
 
 func (obj *memoryObject) Truncate(size int64) error {
+	// the size of objects backed by huge pages must be a multiple of the huge page size.
+	if obj.hugePageSize > 0 {
+		size = (size + obj.hugePageSize - 1) / obj.hugePageSize * obj.hugePageSize
+	}
 	return obj.file.Truncate(size)
 }
 
@@ -93,7 +131,49 @@ func destroyMemoryObject(name string) error {
 		return errors.Wrap(err, "shm name failed")
 	}
 	if err = doDestroyMemoryObject(path); err != nil {
-		err = errors.Wrapf(err, "failed to destroy shm object %q", path)
+		return errors.Wrapf(err, "failed to destroy shm object %q", path)
 	}
-	return err
+	return nil
+}
+
+// NewMemoryObjectFromFd returns an anonymous memory object for the given descriptor,
+// for example, for the one, which was inherited from the parent process.
+// The descriptor must refer to a regular file or a shared memory object.
+// The object takes the ownership of the descriptor. Destroy on such objects only closes them.
+func NewMemoryObjectFromFd(fd uintptr) (*MemoryObject, error) {
+	var st unix.Stat_t
+	if err := unix.Fstat(int(fd), &st); err != nil {
+		return nil, errors.Wrap(os.NewSyscallError("fstat", err), "invalid descriptor")
+	}
+	// pipes, sockets, and other descriptors can't be used as memory objects.
+	if st.Mode&unix.S_IFMT != unix.S_IFREG {
+		return nil, errors.Errorf("descriptor %d is not a memory object", fd)
+	}
+	return newAnonymousMemoryObject(os.NewFile(fd, "fd:"+strconv.Itoa(int(fd)))), nil
+}
+
+// PassMemoryObject adds the object's descriptor to cmd.ExtraFiles, so that it is inherited
+// by the child process. It returns the index, which must be passed to InheritedMemoryObject
+// in the child process. The object must not be closed until the command is started.
+func PassMemoryObject(cmd *exec.Cmd, obj *MemoryObject) int {
+	cmd.ExtraFiles = append(cmd.ExtraFiles, obj.file)
+	return len(cmd.ExtraFiles) - 1
+}
+
+// InheritedMemoryObject returns a memory object, which was passed by the parent process
+// with PassMemoryObject. index is the value, returned by PassMemoryObject.
+func InheritedMemoryObject(index int) (*MemoryObject, error) {
+	if index < 0 {
+		return nil, errors.Errorf("invalid descriptor index %d", index)
+	}
+	// descriptors from exec.Cmd.ExtraFiles start after stdin, stdout, and stderr.
+	return NewMemoryObjectFromFd(uintptr(3 + index))
+}
+
+func newAnonymousMemoryObject(file *os.File) *MemoryObject {
+	impl := &memoryObject{file: file, anonymous: true, hugePageSize: common.HugePageSize(file.Fd())}
+	runtime.SetFinalizer(impl, func(memObject *memoryObject) {
+		memObject.Close()
+	})
+	return &MemoryObject{impl}
 }
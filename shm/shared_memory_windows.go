@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"runtime"
 
+	"github.com/nxgtw/go-ipc/internal/common"
+
 	"github.com/pkg/errors"
 )
 
@@ -38,7 +40,11 @@ func (obj *memoryObject) Destroy() error {
 }
 
 func (obj *memoryObject) Name() string {
-	return filepath.Base(obj.file.Name())
+	result := filepath.Base(obj.file.Name())
+	if name, ok := common.StripNamespace(result); ok {
+		result = name
+	}
+	return result
 }
 
 func (obj *memoryObject) Close() error {
@@ -80,7 +86,7 @@ func shmName(name string) (string, error) {
 	if err != nil {
 		return "", errors.Wrap(err, "failed to get tmp directory name")
 	}
-	return path + "/" + name, nil
+	return path + "/" + common.Namespaced(name), nil
 }
 
 func sharedDirName() (string, error) {
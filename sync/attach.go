@@ -0,0 +1,35 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"os"
+
+	"github.com/nxgtw/go-ipc/internal/attach"
+	"github.com/nxgtw/go-ipc/internal/common"
+)
+
+const (
+	// O_ATTACH flag makes FutexMutex, SpinMutex, and RWMutex register the process in the attach counter
+	// of the object on open, and unregister it on Close. The counter is kept in a separate memory object.
+	// Processes, which exited without closing the object, are removed from the counter, when it is accessed.
+	// Only the processes, which opened the object with this flag, are counted.
+	O_ATTACH = common.O_ATTACH
+	// O_AUTODESTROY flag is the same as O_ATTACH, and, additionally, makes the object destroy itself,
+	// when the last attached process closes it.
+	O_AUTODESTROY = common.O_AUTODESTROY
+)
+
+// openAttachment registers the process in the attach table of the object, if the flag requests it.
+// it must be called before the object is opened.
+func openAttachment(name, typ string, flag int, perm os.FileMode) (*attach.Attachment, error) {
+	return attach.Open(attachTableName(name, typ), flag, perm)
+}
+
+func destroyAttachment(name, typ string) error {
+	return attach.Destroy(attachTableName(name, typ))
+}
+
+func attachTableName(name, typ string) string {
+	return name + ".at" + typ
+}
@@ -0,0 +1,86 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testAttachObjName = "go-ipc.sync-test.attach"
+)
+
+func TestAttachNotRequested(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyRWMutex(testAttachObjName)) {
+		return
+	}
+	m, err := NewRWMutex(testAttachObjName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m.Destroy()
+	_, err = m.Attached()
+	a.Error(err)
+}
+
+func TestAttachCount(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyRWMutex(testAttachObjName)) {
+		return
+	}
+	m, err := NewRWMutex(testAttachObjName, os.O_CREATE|os.O_EXCL|O_ATTACH, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer DestroyRWMutex(testAttachObjName)
+	m2, err := NewRWMutex(testAttachObjName, O_ATTACH, 0666)
+	if !a.NoError(err) {
+		m.Close()
+		return
+	}
+	count, err := m.Attached()
+	a.NoError(err)
+	a.Equal(2, count)
+	a.NoError(m2.Close())
+	count, err = m.Attached()
+	a.NoError(err)
+	a.Equal(1, count)
+	a.NoError(m.Close())
+	// without O_AUTODESTROY the mutex remains.
+	m, err = NewRWMutex(testAttachObjName, 0, 0666)
+	if a.NoError(err) {
+		a.NoError(m.Close())
+	}
+}
+
+func TestAttachAutoDestroy(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroySpinMutex(testAttachObjName)) {
+		return
+	}
+	m, err := NewSpinMutex(testAttachObjName, os.O_CREATE|os.O_EXCL|O_AUTODESTROY, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer DestroySpinMutex(testAttachObjName)
+	m2, err := NewSpinMutex(testAttachObjName, O_AUTODESTROY, 0666)
+	if !a.NoError(err) {
+		m.Close()
+		return
+	}
+	a.NoError(m.Close())
+	count, err := m2.Attached()
+	a.NoError(err)
+	a.Equal(1, count)
+	m, err = NewSpinMutex(testAttachObjName, 0, 0666)
+	if a.NoError(err) {
+		a.NoError(m.Close())
+	}
+	a.NoError(m2.Close())
+	_, err = NewSpinMutex(testAttachObjName, 0, 0666)
+	a.Error(err)
+}
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"os"
 	"time"
+
+	"github.com/nxgtw/go-ipc/internal/common"
 )
 
 var (
@@ -24,12 +26,17 @@ type Cond cond
 //	name - unique condvar name.
 //	flag - a combination of open flags from 'os' package.
 //	perm - object's permission bits.
-//	l - a locker, associated with the shared resource.
+//	l - a locker, associated with the shared resource. It can be any IPCLocker,
+//	including a reader lock of a RWMutex, returned by RWMutex.RLocker.
 func NewCond(name string, flag int, perm os.FileMode, l IPCLocker) (*Cond, error) {
 	c, err := newCond(name, flag, perm, l)
 	if err != nil {
 		return nil, err
 	}
+	if c.stats, err = openStats(name, "cond", perm); err != nil {
+		c.close()
+		return nil, err
+	}
 	return (*Cond)(c), nil
 }
 
@@ -45,25 +52,82 @@ func (c *Cond) Broadcast() {
 
 // Wait waits for the condvar to be signaled.
 func (c *Cond) Wait() {
+	start := c.stats.start()
 	(*cond)(c).wait()
+	c.stats.waited(start, true)
 }
 
 // WaitTimeout waits for the condvar to be signaled for not longer, than timeout.
 func (c *Cond) WaitTimeout(timeout time.Duration) bool {
-	return (*cond)(c).waitTimeout(timeout)
+	start := c.stats.start()
+	return c.stats.waited(start, (*cond)(c).waitTimeout(timeout))
+}
+
+// WaitFor waits for the condvar to be signaled until pred returns true.
+// The locker must be held, when WaitFor is called. pred is called with the locker held.
+func (c *Cond) WaitFor(pred func() bool) {
+	for !pred() {
+		c.Wait()
+	}
+}
+
+// WaitForTimeout waits for the condvar to be signaled until pred returns true,
+// but for not longer, than timeout. It returns the last result of pred.
+// The locker must be held, when WaitForTimeout is called. pred is called with the locker held.
+func (c *Cond) WaitForTimeout(pred func() bool, timeout time.Duration) bool {
+	result := pred()
+	if result {
+		return true
+	}
+	common.CallTimeout(func(timeout time.Duration) bool {
+		if timeout >= 0 {
+			c.WaitTimeout(timeout)
+		} else {
+			c.Wait()
+		}
+		// if the predicate is still false, this was a spurious wakeup, and we can continue waiting.
+		result = pred()
+		return !result
+	}, timeout)
+	return result
+}
+
+// Stats returns wait time metrics of the condvar. Time to reacquire the locker is included.
+// It returns an error, if the condvar was opened with stats disabled. See SetStatsEnabled for details.
+func (c *Cond) Stats() (Stats, error) {
+	return c.stats.stats()
 }
 
 // Close releases resources of the cond's shared state.
 func (c *Cond) Close() error {
-	return (*cond)(c).close()
+	e1, e2 := (*cond)(c).close(), c.stats.close()
+	if e1 != nil {
+		return e1
+	}
+	return e2
 }
 
 // Destroy permanently removes condvar.
 func (c *Cond) Destroy() error {
-	return (*cond)(c).destroy()
+	e1, e2 := c.stats.close(), (*cond)(c).destroy()
+	var e3 error
+	// if the condvar was placed in a memory region, it has no named objects.
+	if len(c.name) > 0 {
+		e3 = destroyStats(c.name, "cond")
+	}
+	if e1 != nil {
+		return e1
+	}
+	if e2 != nil {
+		return e2
+	}
+	return e3
 }
 
 // DestroyCond permanently removes condvar with the given name.
 func DestroyCond(name string) error {
-	return destroyCond(name)
+	if err := destroyCond(name); err != nil {
+		return err
+	}
+	return destroyStats(name, "cond")
 }
@@ -11,17 +11,26 @@ import (
 	"github.com/nxgtw/go-ipc/internal/allocator"
 	"github.com/nxgtw/go-ipc/internal/common"
 	"github.com/nxgtw/go-ipc/internal/helper"
-	"bitbucket.org/avd/go-ipc/mmf"
-	"bitbucket.org/avd/go-ipc/shm"
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
 	"github.com/pkg/errors"
 )
 
+const (
+	// CondSize is the number of bytes occupied by a Cond placed in a memory region.
+	CondSize = condStateSize
+
+	// condStateSize is the size of the futex, which holds the sequence number of signals.
+	condStateSize = 4
+)
+
 // cond is a futex-based convar.
 type cond struct {
 	L      IPCLocker
 	name   string
 	region *mmf.MemoryRegion
 	ftx    *futex
+	stats  *statsRecorder
 }
 
 func newCond(name string, flag int, perm os.FileMode, l IPCLocker) (*cond, error) {
@@ -29,7 +38,7 @@ func newCond(name string, flag int, perm os.FileMode, l IPCLocker) (*cond, error
 		return nil, err
 	}
 
-	region, _, err := helper.CreateWritableRegion(condSharedStateName(name), flag, perm, lwmStateSize)
+	region, _, err := helper.CreateWritableRegion(condSharedStateName(name), flag, perm, condStateSize)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create shared state")
 	}
@@ -44,6 +53,25 @@ func newCond(name string, flag int, perm os.FileMode, l IPCLocker) (*cond, error
 	return result, nil
 }
 
+// NewCondAt returns a condvar placed in the region at the given offset.
+// Such a condvar does not own any named objects, so Close and Destroy do nothing,
+// and the region must not be closed while the condvar is in use.
+//	region - memory region, which holds the condvar. it must be writable.
+//	offset - offset of the condvar in the region. it must be 4-byte aligned.
+//	init - if true, the memory is initialized.
+//	l - a locker, associated with the shared resource.
+func NewCondAt(region *mmf.MemoryRegion, offset int, init bool, l IPCLocker) (*Cond, error) {
+	state, err := regionPointerAt(region, offset, CondSize, 4)
+	if err != nil {
+		return nil, err
+	}
+	result := &cond{L: l, ftx: &futex{ptr: state}}
+	if init {
+		*result.ftx.addr() = 0
+	}
+	return (*Cond)(result), nil
+}
+
 func (c *cond) signal() {
 	c.ftx.add(1)
 	_, err := c.ftx.wake(1)
@@ -54,6 +82,9 @@ func (c *cond) signal() {
 
 func (c *cond) broadcast() {
 	c.ftx.add(1)
+	if m, ok := c.L.(*FutexMutex); ok && c.requeueAll(m) {
+		return
+	}
 	_, err := c.ftx.wakeAll()
 	if err != nil {
 		panic(err)
@@ -66,7 +97,7 @@ func (c *cond) wait() {
 	if err := c.ftx.wait(seq, time.Duration(-1)); err != nil {
 		panic(err)
 	}
-	c.L.Lock()
+	c.relock()
 }
 
 func (c *cond) waitTimeout(timeout time.Duration) bool {
@@ -78,11 +109,14 @@ func (c *cond) waitTimeout(timeout time.Duration) bool {
 	} else if !common.IsTimeoutErr(err) {
 		panic(err)
 	}
-	c.L.Lock()
+	c.relock()
 	return success
 }
 
 func (c *cond) close() error {
+	if c.region == nil {
+		return nil
+	}
 	if err := c.region.Close(); err != nil {
 		return errors.Wrap(err, "failed to close waiters list memory region")
 	}
@@ -90,6 +124,9 @@ func (c *cond) close() error {
 }
 
 func (c *cond) destroy() error {
+	if c.region == nil {
+		return nil
+	}
 	var result error
 	if err := c.close(); err != nil {
 		result = errors.Wrap(err, "destroy failed")
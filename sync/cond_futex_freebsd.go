@@ -0,0 +1,12 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+// requeueAll is not supported on freebsd, as umtx has no requeue operation.
+func (c *cond) requeueAll(m *FutexMutex) bool {
+	return false
+}
+
+func (c *cond) relock() {
+	c.L.Lock()
+}
@@ -0,0 +1,41 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/common"
+
+	"golang.org/x/sys/unix"
+)
+
+// requeueAll wakes one waiter and moves all the others to the futex of the mutex,
+// so that they are woken one by one, as the mutex is being unlocked, instead of
+// all of them waking up at once and contending for the mutex.
+// this works only if all the waiters use the same mutex.
+func (c *cond) requeueAll(m *FutexMutex) bool {
+	for {
+		seq := atomic.LoadInt32(c.ftx.addr())
+		_, err := FutexCmpRequeue(c.ftx.ptr, seq, 1, cFutexWakeAll, unsafe.Pointer(m.lwm.state), 0)
+		if err == nil {
+			return true
+		}
+		// the sequence has been changed by another signal or broadcast. try again.
+		if !common.SyscallErrHasCode(err, unix.EAGAIN) {
+			panic(err)
+		}
+	}
+}
+
+// relock locks cond's locker after a wait.
+// if the locker is a FutexMutex, the waiter might have been requeued onto its futex,
+// so it must leave the mutex in contended state for the next requeued waiter to be woken.
+func (c *cond) relock() {
+	if m, ok := c.L.(*FutexMutex); ok {
+		m.lwm.lockContended()
+		return
+	}
+	c.L.Lock()
+}
@@ -0,0 +1,128 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build linux freebsd
+
+package sync
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCondAt(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(shm.DestroyMemoryObject(testMemObj)) {
+		return
+	}
+	defer shm.DestroyMemoryObject(testMemObj)
+	region, err := createMemoryRegionSimple(os.O_CREATE|os.O_EXCL|os.O_RDWR, mmf.MEM_READWRITE, 64, 0)
+	if !a.NoError(err) {
+		return
+	}
+	defer region.Close()
+	// the record consists of a mutex, a condvar, and a flag.
+	m, err := NewFutexMutexAt(region, 0, true)
+	if !a.NoError(err) {
+		return
+	}
+	cond, err := NewCondAt(region, FutexMutexSize, true, m)
+	if !a.NoError(err) {
+		return
+	}
+	flag := &region.Data()[FutexMutexSize+CondSize]
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		m.Lock()
+		*flag = 1
+		m.Unlock()
+		cond.Broadcast()
+	}()
+	m.Lock()
+	a.True(cond.WaitForTimeout(func() bool {
+		return *flag == 1
+	}, time.Second))
+	m.Unlock()
+	a.NoError(cond.Destroy())
+	a.NoError(m.Destroy())
+}
+
+func testCondBroadcastFutexMutex(t *testing.T, plain bool) {
+	const waiters = 16
+	a := assert.New(t)
+	a.NoError(DestroyCond(testCondName))
+	if !a.NoError(DestroyFutexMutex(testCondMutName)) {
+		return
+	}
+	m, err := NewFutexMutex(testCondMutName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(m.Destroy())
+	}()
+	var l IPCLocker = m
+	if plain {
+		l = plainLocker{m}
+	}
+	cond, err := NewCond(testCondName, os.O_CREATE|os.O_EXCL, 0666, l)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(cond.Destroy())
+	}()
+	var ready, woken int
+	var wg sync.WaitGroup
+	wg.Add(waiters)
+	for i := 0; i < waiters; i++ {
+		go func() {
+			defer wg.Done()
+			m.Lock()
+			ready++
+			cond.Wait()
+			woken++
+			m.Unlock()
+		}()
+	}
+	// the waiters release the mutex only in Wait, so when all of them are ready,
+	// and the mutex is held by this goroutine, all of them are waiting.
+	for {
+		m.Lock()
+		if ready == waiters {
+			break
+		}
+		m.Unlock()
+		time.Sleep(time.Millisecond * 10)
+	}
+	cond.Broadcast()
+	m.Unlock()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Errorf("not all the waiters have been woken")
+		return
+	}
+	m.Lock()
+	a.Equal(waiters, woken)
+	m.Unlock()
+}
+
+func TestCondBroadcastFutexMutex(t *testing.T) {
+	testCondBroadcastFutexMutex(t, false)
+}
+
+func TestCondBroadcastFutexMutexNoRequeue(t *testing.T) {
+	testCondBroadcastFutexMutex(t, true)
+}
@@ -5,6 +5,7 @@ package sync
 import (
 	"os"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -104,6 +105,110 @@ func TestCondMissedSignal(t *testing.T) {
 	a.False(cond.WaitTimeout(0))
 }
 
+func TestCondWaitFor(t *testing.T) {
+	a := assert.New(t)
+	cond, l, err := makeTestCond(a)
+	if err != nil {
+		return
+	}
+	defer destroyTestCond(a, cond, l)
+	var value int
+	go func() {
+		for i := 0; i < 3; i++ {
+			time.Sleep(time.Millisecond * 20)
+			l.Lock()
+			value++
+			l.Unlock()
+			cond.Broadcast()
+		}
+	}()
+	l.Lock()
+	cond.WaitFor(func() bool {
+		return value == 3
+	})
+	a.Equal(3, value)
+	l.Unlock()
+}
+
+func TestCondWaitForTimeout(t *testing.T) {
+	a := assert.New(t)
+	cond, l, err := makeTestCond(a)
+	if err != nil {
+		return
+	}
+	defer destroyTestCond(a, cond, l)
+	var value int
+	l.Lock()
+	a.True(cond.WaitForTimeout(func() bool {
+		return value == 0
+	}, 0))
+	go func() {
+		for {
+			time.Sleep(time.Millisecond * 10)
+			l.Lock()
+			value++
+			done := value == 5
+			l.Unlock()
+			cond.Broadcast()
+			if done {
+				return
+			}
+		}
+	}()
+	// spurious wakeups must not interrupt waiting before timeout.
+	start := time.Now()
+	a.False(cond.WaitForTimeout(func() bool {
+		return value < 0
+	}, time.Millisecond*30))
+	a.True(time.Since(start) >= time.Millisecond*30)
+	a.True(cond.WaitForTimeout(func() bool {
+		return value == 5
+	}, time.Second*3))
+	l.Unlock()
+}
+
+func TestCondRLocker(t *testing.T) {
+	const condName = "ipccondrw"
+	a := assert.New(t)
+	if !a.NoError(DestroyRWMutex(testCondMutName)) || !a.NoError(DestroyCond(condName)) {
+		return
+	}
+	m, err := NewRWMutex(testCondMutName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(m.Destroy())
+	}()
+	cond, err := NewCond(condName, os.O_CREATE|os.O_EXCL, 0666, m.RLocker())
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(cond.Destroy())
+	}()
+	var value int32
+	var wg sync.WaitGroup
+	wg.Add(4)
+	for i := 0; i < 4; i++ {
+		go func() {
+			defer wg.Done()
+			m.RLock()
+			cond.WaitFor(func() bool {
+				return atomic.LoadInt32(&value) == 1
+			})
+			m.RUnlock()
+		}()
+	}
+	time.Sleep(time.Millisecond * 50)
+	// the writer can acquire the mutex, as readers release it while waiting.
+	m.Lock()
+	atomic.StoreInt32(&value, 1)
+	m.Unlock()
+	cond.Broadcast()
+	a.True(testutil.WaitForFunc(wg.Wait, time.Second*3))
+}
+
 func TestCondSignalAnotherProcess(t *testing.T) {
 	a := assert.New(t)
 	cond, l, err := makeTestCond(a)
@@ -196,3 +301,76 @@ func TestCondWaitAnotherProcess(t *testing.T) {
 		t.Errorf("timeout")
 	}
 }
+
+// plainLocker hides the type of the locker from the condvar,
+// so that it could not apply locker-specific optimizations.
+type plainLocker struct {
+	IPCLocker
+}
+
+func benchmarkCondBroadcastAnotherProcess(b *testing.B, plain bool) {
+	const (
+		waiters   = 8
+		readySema = "condReadySema"
+	)
+	a := assert.New(b)
+	cond, l, err := makeTestCond(a)
+	if err != nil {
+		return
+	}
+	defer destroyTestCond(a, cond, l)
+	if plain {
+		c, err := NewCond(testCondName, 0, 0666, plainLocker{l})
+		if !a.NoError(err) {
+			return
+		}
+		defer c.Close()
+		cond = c
+	}
+	if !a.NoError(DestroySemaphore(readySema)) {
+		return
+	}
+	s, err := NewSemaphore(readySema, os.O_CREATE|os.O_EXCL, 0666, 0)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(s.Close())
+		a.NoError(DestroySemaphore(readySema))
+	}()
+	var results []<-chan testutil.TestAppResult
+	args := argsForCondWaitNCommand(readySema, testCondName, testCondMutName, b.N)
+	for i := 0; i < waiters; i++ {
+		results = append(results, testutil.RunTestAppAsync(args, nil))
+	}
+	for i := 0; i < waiters; i++ {
+		if !a.True(s.WaitTimeout(time.Second*10), "waiters are not ready") {
+			return
+		}
+	}
+	b.ResetTimer()
+	for _, ch := range results {
+	loop:
+		for {
+			select {
+			case res := <-ch:
+				if res.Err != nil {
+					b.Errorf("app error: %v. the output is %q", res.Err, res.Output)
+				}
+				break loop
+			default:
+				l.Lock()
+				cond.Broadcast()
+				l.Unlock()
+			}
+		}
+	}
+}
+
+func BenchmarkCondBroadcastAnotherProcess(b *testing.B) {
+	benchmarkCondBroadcastAnotherProcess(b, false)
+}
+
+func BenchmarkCondBroadcastAnotherProcessNoRequeue(b *testing.B) {
+	benchmarkCondBroadcastAnotherProcess(b, true)
+}
@@ -11,8 +11,8 @@ import (
 	"github.com/nxgtw/go-ipc/internal/allocator"
 	"github.com/nxgtw/go-ipc/internal/array"
 	"github.com/nxgtw/go-ipc/internal/helper"
-	"bitbucket.org/avd/go-ipc/mmf"
-	"bitbucket.org/avd/go-ipc/shm"
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
 	"github.com/pkg/errors"
 )
 
@@ -23,6 +23,7 @@ type cond struct {
 	name          string
 	waitersRegion *mmf.MemoryRegion
 	waiters       *array.SharedArray
+	stats         *statsRecorder
 }
 
 func newCond(name string, flag int, perm os.FileMode, l IPCLocker) (*cond, error) {
@@ -0,0 +1,150 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// LockHolder describes an owner or a waiter of an ipc locker, recorded by the debug registry.
+type LockHolder struct {
+	// Lock is the name of the locker.
+	Lock string
+	// Pid is the id of the process, which holds the locker, or waits for it.
+	Pid int
+	// Tid is the id of the thread, on which the locker was acquired, or is being waited for.
+	Tid int64
+	// Goroutine is the id of the goroutine, which acquired the locker, or waits for it.
+	Goroutine int64
+	// StackHash identifies the call stack, from which the locker was acquired, or is being waited for.
+	StackHash uint64
+	// Since is the time, when the locker was acquired, or when the wait began.
+	Since time.Time
+	// Waiting is true, if the locker is being waited for, rather than held.
+	Waiting bool
+}
+
+// DeadlockError describes a cycle of waiting goroutines, found by the debug registry.
+// Each holder in Cycle waits for a lock, which is held by the next holder,
+// and the lock of the last one is held by the first one.
+type DeadlockError struct {
+	Cycle []LockHolder
+}
+
+func (e *DeadlockError) Error() string {
+	var buff bytes.Buffer
+	buff.WriteString("deadlock detected:")
+	for i, h := range e.Cycle {
+		next := e.Cycle[(i+1)%len(e.Cycle)]
+		fmt.Fprintf(&buff, " [pid %d goroutine %d (tid %d, stack %016x) waits for %q held by pid %d goroutine %d]",
+			h.Pid, h.Goroutine, h.Tid, h.StackHash, h.Lock, next.Pid, next.Goroutine)
+	}
+	return buff.String()
+}
+
+var (
+	errDebugDisabled = errors.New("lock debugging is disabled. rebuild with 'ipc_debug' tag")
+
+	deadlockHandlerMu sync.Mutex
+	deadlockHandler   = func(err *DeadlockError) {
+		fmt.Fprintln(os.Stderr, err)
+	}
+)
+
+// DebugEnabled returns true, if the package was built with 'ipc_debug' tag.
+// In this mode mutexes and rwmutexes record their holders and waiters in a shared registry,
+// which can be inspected with DumpHolders. Before blocking on a locker,
+// the registry is checked for a wait cycle across all processes, and
+// the deadlock handler is called, if one is found.
+// PIMutex is not tracked, as the kernel detects deadlocks for it.
+func DebugEnabled() bool {
+	return debugEnabled
+}
+
+// SetDeadlockHandler sets a function, which is called in debug mode, when a deadlock is detected.
+// The handler is called by the goroutine, which is about to block. If the handler returns,
+// the goroutine proceeds to wait. The default handler prints the error to stderr.
+func SetDeadlockHandler(handler func(err *DeadlockError)) {
+	deadlockHandlerMu.Lock()
+	deadlockHandler = handler
+	deadlockHandlerMu.Unlock()
+}
+
+func onDeadlock(err *DeadlockError) {
+	deadlockHandlerMu.Lock()
+	handler := deadlockHandler
+	deadlockHandlerMu.Unlock()
+	if handler != nil {
+		handler(err)
+	}
+}
+
+// DumpHolders returns all holders and waiters of all ipc lockers, recorded by the debug registry.
+// The registry is shared between processes, so entries from all processes are returned.
+// It returns an error, if the package was built without 'ipc_debug' tag.
+func DumpHolders() ([]LockHolder, error) {
+	return dumpHolders()
+}
+
+// LockWatchdog periodically inspects the debug registry and reports lockers, held for too long.
+type LockWatchdog struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// StartLockWatchdog starts a watchdog, which reports lockers, held longer, than threshold.
+// Every long-held lock is reported once per acquisition.
+// It returns an error, if the package was built without 'ipc_debug' tag.
+//	threshold - max time a locker can be held, before it is reported.
+//	interval - how often the registry is checked.
+//	report - a function, which is called for each long-held locker.
+func StartLockWatchdog(threshold, interval time.Duration, report func(h LockHolder)) (*LockWatchdog, error) {
+	if !debugEnabled {
+		return nil, errDebugDisabled
+	}
+	w := &LockWatchdog{stop: make(chan struct{}), done: make(chan struct{})}
+	go w.run(threshold, interval, report)
+	return w, nil
+}
+
+// Stop stops the watchdog and waits for it to exit.
+func (w *LockWatchdog) Stop() {
+	close(w.stop)
+	<-w.done
+}
+
+func (w *LockWatchdog) run(threshold, interval time.Duration, report func(h LockHolder)) {
+	defer close(w.done)
+	reported := make(map[LockHolder]struct{})
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+		holders, err := dumpHolders()
+		if err != nil {
+			continue
+		}
+		now := time.Now()
+		current := make(map[LockHolder]struct{})
+		for _, h := range holders {
+			if h.Waiting || now.Sub(h.Since) < threshold {
+				continue
+			}
+			current[h] = struct{}{}
+			if _, ok := reported[h]; !ok {
+				report(h)
+			}
+		}
+		reported = current
+	}
+}
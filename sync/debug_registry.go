@@ -0,0 +1,431 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build ipc_debug
+
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/common"
+	"github.com/nxgtw/go-ipc/internal/helper"
+	"github.com/nxgtw/go-ipc/mmf"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	debugEnabled = true
+
+	// debug registry is a shared memory object with the following layout:
+	//	guard (pid of the owner) | padding | entries
+	debugRegistryName        = "go-ipc.debug.registry"
+	debugRegistryHeaderSize  = 64
+	debugRegistryEntries     = 1024
+	debugRegistryEntrySize   = int(unsafe.Sizeof(debugEntry{}))
+	debugRegistrySize        = debugRegistryHeaderSize + debugRegistryEntries*debugRegistryEntrySize
+	debugRegistryMaxStackLen = 32
+	// debugRegistryMaxCycleLen limits the length of a cycle findCycle looks for.
+	debugRegistryMaxCycleLen = 64
+	// debugGuardCheckPeriod is the number of spins, after which the owner of the guard is checked for being alive.
+	debugGuardCheckPeriod = 1000
+
+	debugEntryFree    = int32(0)
+	debugEntryHeld    = int32(1)
+	debugEntryWaiting = int32(2)
+)
+
+// debugEntry is a record in the registry. its size is 128 bytes.
+type debugEntry struct {
+	state  int32
+	pid    int32
+	tid    int64
+	gid    int64
+	stack  uint64
+	since  int64
+	lockID uint64
+	name   [80]byte
+}
+
+type debugOwner struct {
+	pid int32
+	gid int64
+}
+
+func (e *debugEntry) owner() debugOwner {
+	return debugOwner{pid: e.pid, gid: e.gid}
+}
+
+func (e *debugEntry) holder() LockHolder {
+	name := e.name[:]
+	if idx := bytes.IndexByte(name, 0); idx >= 0 {
+		name = name[:idx]
+	}
+	return LockHolder{
+		Lock:      string(name),
+		Pid:       int(e.pid),
+		Tid:       e.tid,
+		Goroutine: e.gid,
+		StackHash: e.stack,
+		Since:     time.Unix(0, e.since),
+		Waiting:   e.state == debugEntryWaiting,
+	}
+}
+
+type debugRegistry struct {
+	region  *mmf.MemoryRegion
+	guard   debugGuard
+	entries *[debugRegistryEntries]debugEntry
+	// full is set, when an entry could not be added, so that it is reported once.
+	full bool
+}
+
+var (
+	registryOnce sync.Once
+	registry     *debugRegistry
+	registryErr  error
+)
+
+// debugGuard is a spin lock, which protects the registry. it holds the pid of its owner,
+// so that it could be taken over, if the owner died inside a critical section.
+type debugGuard struct {
+	state *int32
+}
+
+func (g debugGuard) lock() {
+	self := int32(os.Getpid())
+	for i := 1; ; i++ {
+		old := atomic.LoadInt32(g.state)
+		if old == 0 {
+			if atomic.CompareAndSwapInt32(g.state, 0, self) {
+				return
+			}
+			continue
+		}
+		if old != self && i%debugGuardCheckPeriod == 0 && !common.ProcessAlive(int(old)) {
+			if atomic.CompareAndSwapInt32(g.state, old, self) {
+				return
+			}
+		}
+		runtime.Gosched()
+	}
+}
+
+func (g debugGuard) unlock() {
+	atomic.StoreInt32(g.state, 0)
+}
+
+// openDebugRegistry opens the registry once per process.
+// if it fails, locks are not tracked.
+func openDebugRegistry() *debugRegistry {
+	registryOnce.Do(func() {
+		region, _, err := helper.CreateWritableRegion(debugRegistryName, os.O_CREATE, 0666, debugRegistrySize)
+		if err != nil {
+			registryErr = errors.Wrap(err, "failed to open debug registry")
+			return
+		}
+		data := allocator.ByteSliceData(region.Data())
+		registry = &debugRegistry{
+			region:  region,
+			guard:   debugGuard{state: (*int32)(data)},
+			entries: (*[debugRegistryEntries]debugEntry)(unsafe.Pointer(uintptr(data) + debugRegistryHeaderSize)),
+		}
+	})
+	return registry
+}
+
+// find returns an entry of the owner for the lock in the given state.
+// if gid is 0, an entry of any goroutine of the process is returned.
+func (r *debugRegistry) find(lockID uint64, state int32, pid int32, gid int64) *debugEntry {
+	for i := range r.entries {
+		e := &r.entries[i]
+		if e.state == state && e.lockID == lockID && e.pid == pid && (gid == 0 || e.gid == gid) {
+			return e
+		}
+	}
+	return nil
+}
+
+// add records the owner in a free entry. if there are no free entries,
+// entries of dead processes are reclaimed. if the registry is still full, it is reported, and nil is returned.
+func (r *debugRegistry) add(d lockDebug, state int32, owner debugOwner, stack uint64) *debugEntry {
+	e := r.free()
+	if e == nil && r.reclaim() > 0 {
+		e = r.free()
+	}
+	if e == nil {
+		if !r.full {
+			r.full = true
+			fmt.Fprintf(os.Stderr, "go-ipc: debug registry is full, %q and other locks are not tracked\n", d.name)
+		}
+		return nil
+	}
+	r.full = false
+	*e = debugEntry{
+		state:  state,
+		pid:    owner.pid,
+		tid:    currentThreadID(),
+		gid:    owner.gid,
+		stack:  stack,
+		since:  time.Now().UnixNano(),
+		lockID: d.id,
+	}
+	copy(e.name[:len(e.name)-1], d.name)
+	return e
+}
+
+func (r *debugRegistry) free() *debugEntry {
+	for i := range r.entries {
+		if e := &r.entries[i]; e.state == debugEntryFree {
+			return e
+		}
+	}
+	return nil
+}
+
+// reclaim frees entries of the processes, which have died without releasing their locks.
+// it returns the number of freed entries.
+func (r *debugRegistry) reclaim() int {
+	self := int32(os.Getpid())
+	alive := make(processAliveCache)
+	var result int
+	for i := range r.entries {
+		e := &r.entries[i]
+		if e.state == debugEntryFree || e.pid == self {
+			continue
+		}
+		if !alive.check(e.pid) {
+			e.state = debugEntryFree
+			result++
+		}
+	}
+	return result
+}
+
+// processAliveCache remembers, which processes are alive, so that each of them is checked once.
+type processAliveCache map[int32]bool
+
+func (c processAliveCache) check(pid int32) bool {
+	alive, checked := c[pid]
+	if !checked {
+		alive = common.ProcessAlive(int(pid))
+		c[pid] = alive
+	}
+	return alive
+}
+
+// findCycle looks for a chain of waiters starting with the given entry,
+// which leads back to its owner. it returns waiting entries, which form the cycle.
+// the registry is indexed once, and every owner is visited once, so the scan is linear,
+// and cycles longer, than debugRegistryMaxCycleLen, are not detected.
+func (r *debugRegistry) findCycle(start *debugEntry) []*debugEntry {
+	holders := make(map[uint64][]debugOwner)
+	waits := make(map[debugOwner][]*debugEntry)
+	for i := range r.entries {
+		e := &r.entries[i]
+		switch e.state {
+		case debugEntryHeld:
+			holders[e.lockID] = append(holders[e.lockID], e.owner())
+		case debugEntryWaiting:
+			waits[e.owner()] = append(waits[e.owner()], e)
+		}
+	}
+	me := start.owner()
+	visited := make(map[debugOwner]bool)
+	alive := make(processAliveCache)
+	path := []*debugEntry{start}
+	var walk func(lockID uint64) bool
+	walk = func(lockID uint64) bool {
+		if len(path) > debugRegistryMaxCycleLen {
+			return false
+		}
+		for _, owner := range holders[lockID] {
+			if owner == me {
+				return true
+			}
+			if visited[owner] {
+				continue
+			}
+			visited[owner] = true
+			// a process could die, holding the lock. it can't be a part of a deadlock.
+			if owner.pid != me.pid && !alive.check(owner.pid) {
+				continue
+			}
+			for _, waiting := range waits[owner] {
+				path = append(path, waiting)
+				if walk(waiting.lockID) {
+					return true
+				}
+				path = path[:len(path)-1]
+			}
+		}
+		return false
+	}
+	if walk(start.lockID) {
+		return path
+	}
+	return nil
+}
+
+// lockDebug records operations on a named locker in the registry.
+// lockers with an empty name are not tracked.
+type lockDebug struct {
+	id   uint64
+	name string
+}
+
+// newLockDebug returns a debug recorder for a locker. lock id is a hash of the kind and the name,
+// so that lockers of different types with the same name are not confused.
+func newLockDebug(kind, name string) lockDebug {
+	if len(name) == 0 {
+		return lockDebug{}
+	}
+	h := fnv.New64a()
+	h.Write([]byte(kind))
+	h.Write([]byte{0})
+	h.Write([]byte(name))
+	return lockDebug{id: h.Sum64(), name: name}
+}
+
+// wait records, that the caller is going to wait for the lock,
+// and calls deadlock handler, if this wait closes a cycle.
+func (d lockDebug) wait() {
+	r := d.registry()
+	if r == nil {
+		return
+	}
+	owner, stack := currentDebugOwner(), callerStackHash()
+	var err *DeadlockError
+	r.guard.lock()
+	if e := r.add(d, debugEntryWaiting, owner, stack); e != nil {
+		if cycle := r.findCycle(e); len(cycle) > 0 {
+			err = &DeadlockError{Cycle: make([]LockHolder, 0, len(cycle))}
+			for _, c := range cycle {
+				err.Cycle = append(err.Cycle, c.holder())
+			}
+		}
+	}
+	r.guard.unlock()
+	if err != nil {
+		onDeadlock(err)
+	}
+}
+
+// acquired records, that the caller holds the lock.
+func (d lockDebug) acquired() {
+	r := d.registry()
+	if r == nil {
+		return
+	}
+	owner, stack := currentDebugOwner(), callerStackHash()
+	r.guard.lock()
+	if e := r.find(d.id, debugEntryWaiting, owner.pid, owner.gid); e != nil {
+		e.state = debugEntryHeld
+		e.tid = currentThreadID()
+		e.since = time.Now().UnixNano()
+	} else {
+		r.add(d, debugEntryHeld, owner, stack)
+	}
+	r.guard.unlock()
+}
+
+// cancel removes caller's waiting record, if the lock has not been acquired.
+func (d lockDebug) cancel() {
+	r := d.registry()
+	if r == nil {
+		return
+	}
+	owner := currentDebugOwner()
+	r.guard.lock()
+	if e := r.find(d.id, debugEntryWaiting, owner.pid, owner.gid); e != nil {
+		e.state = debugEntryFree
+	}
+	r.guard.unlock()
+}
+
+// released removes a record of the lock holder. as a lock can be
+// released by another goroutine, any holder from the current process is removed,
+// if the caller does not hold the lock.
+func (d lockDebug) released() {
+	r := d.registry()
+	if r == nil {
+		return
+	}
+	owner := currentDebugOwner()
+	r.guard.lock()
+	e := r.find(d.id, debugEntryHeld, owner.pid, owner.gid)
+	if e == nil {
+		e = r.find(d.id, debugEntryHeld, owner.pid, 0)
+	}
+	if e != nil {
+		e.state = debugEntryFree
+	}
+	r.guard.unlock()
+}
+
+func (d lockDebug) registry() *debugRegistry {
+	if d.id == 0 {
+		return nil
+	}
+	return openDebugRegistry()
+}
+
+func dumpHolders() ([]LockHolder, error) {
+	r := openDebugRegistry()
+	if r == nil {
+		return nil, registryErr
+	}
+	var result []LockHolder
+	r.guard.lock()
+	r.reclaim()
+	for i := range r.entries {
+		if e := &r.entries[i]; e.state != debugEntryFree {
+			result = append(result, e.holder())
+		}
+	}
+	r.guard.unlock()
+	return result, nil
+}
+
+func currentDebugOwner() debugOwner {
+	return debugOwner{pid: int32(os.Getpid()), gid: currentGoroutineID()}
+}
+
+// currentGoroutineID parses goroutine id from the header of its stack trace: 'goroutine 1 [running]:'.
+func currentGoroutineID() int64 {
+	var buf [64]byte
+	data := buf[:runtime.Stack(buf[:], false)]
+	data = bytes.TrimPrefix(data, []byte("goroutine "))
+	if idx := bytes.IndexByte(data, ' '); idx > 0 {
+		data = data[:idx]
+	}
+	id, _ := strconv.ParseInt(string(data), 10, 64)
+	return id
+}
+
+// callerStackHash returns a hash of functions and lines of the caller's stack.
+// it doesn't depend on actual addresses, so it's the same for the same call site in different processes.
+func callerStackHash() uint64 {
+	var pcs [debugRegistryMaxStackLen]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs[:])])
+	h := fnv.New64a()
+	for {
+		frame, more := frames.Next()
+		h.Write([]byte(frame.Function))
+		h.Write([]byte(strconv.Itoa(frame.Line)))
+		if !more {
+			break
+		}
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,21 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build !ipc_debug
+
+package sync
+
+const debugEnabled = false
+
+// lockDebug is a no-op in release builds.
+type lockDebug struct{}
+
+func newLockDebug(kind, name string) lockDebug { return lockDebug{} }
+
+func (d lockDebug) wait()     {}
+func (d lockDebug) acquired() {}
+func (d lockDebug) cancel()   {}
+func (d lockDebug) released() {}
+
+func dumpHolders() ([]LockHolder, error) {
+	return nil, errDebugDisabled
+}
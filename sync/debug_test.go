@@ -0,0 +1,230 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build ipc_debug
+
+package sync
+
+import (
+	"os"
+	"os/exec"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testDebugMutexName  = "go-ipc.sync-test.debug.a"
+	testDebugMutexName2 = "go-ipc.sync-test.debug.b"
+)
+
+func findHolder(name string, waiting bool) (LockHolder, bool) {
+	holders, err := DumpHolders()
+	if err != nil {
+		return LockHolder{}, false
+	}
+	for _, h := range holders {
+		if h.Lock == name && h.Pid == os.Getpid() && h.Waiting == waiting {
+			return h, true
+		}
+	}
+	return LockHolder{}, false
+}
+
+func TestDumpHolders(t *testing.T) {
+	a := assert.New(t)
+	a.True(DebugEnabled())
+	if !a.NoError(DestroyMutex(testDebugMutexName)) {
+		return
+	}
+	m, err := NewMutex(testDebugMutexName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer DestroyMutex(testDebugMutexName)
+	defer m.Close()
+	_, found := findHolder(testDebugMutexName, false)
+	a.False(found)
+	m.Lock()
+	h, found := findHolder(testDebugMutexName, false)
+	if a.True(found) {
+		a.Equal(currentThreadID() != 0, h.Tid != 0)
+		a.NotEqual(0, h.Goroutine)
+		a.NotEqual(0, h.StackHash)
+		a.WithinDuration(time.Now(), h.Since, time.Second)
+	}
+	m.Unlock()
+	_, found = findHolder(testDebugMutexName, false)
+	a.False(found)
+}
+
+func TestDeadlockDetection(t *testing.T) {
+	a := assert.New(t)
+	for _, name := range []string{testDebugMutexName, testDebugMutexName2} {
+		if !a.NoError(DestroyMutex(name)) {
+			return
+		}
+		defer DestroyMutex(name)
+	}
+	m1, err := NewMutex(testDebugMutexName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m1.Close()
+	m2, err := NewMutex(testDebugMutexName2, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m2.Close()
+	detected := make(chan *DeadlockError, 2)
+	SetDeadlockHandler(func(err *DeadlockError) {
+		detected <- err
+	})
+	defer SetDeadlockHandler(nil)
+	m1.Lock()
+	locked, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		m2.Lock()
+		close(locked)
+		// waits for the first mutex, which is held by the test goroutine.
+		m1.Lock()
+		m1.Unlock()
+		m2.Unlock()
+	}()
+	<-locked
+	for {
+		if _, found := findHolder(testDebugMutexName, true); found {
+			break
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+	// this closes the cycle.
+	a.False(m2.LockTimeout(time.Millisecond * 50))
+	select {
+	case err := <-detected:
+		if a.Len(err.Cycle, 2) {
+			a.Equal(testDebugMutexName2, err.Cycle[0].Lock)
+			a.Equal(testDebugMutexName, err.Cycle[1].Lock)
+			a.True(err.Cycle[0].Waiting && err.Cycle[1].Waiting)
+		}
+	default:
+		a.Fail("deadlock was not detected")
+	}
+	m1.Unlock()
+	<-done
+}
+
+func TestLockWatchdog(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyMutex(testDebugMutexName)) {
+		return
+	}
+	m, err := NewMutex(testDebugMutexName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer DestroyMutex(testDebugMutexName)
+	defer m.Close()
+	reported := make(chan LockHolder, 16)
+	w, err := StartLockWatchdog(time.Millisecond*50, time.Millisecond*10, func(h LockHolder) {
+		if h.Lock == testDebugMutexName && h.Pid == os.Getpid() {
+			reported <- h
+		}
+	})
+	if !a.NoError(err) {
+		return
+	}
+	m.Lock()
+	select {
+	case h := <-reported:
+		a.True(time.Since(h.Since) >= time.Millisecond*50)
+	case <-time.After(time.Second * 2):
+		a.Fail("long-held lock was not reported")
+	}
+	m.Unlock()
+	w.Stop()
+	a.Len(reported, 0)
+}
+
+func TestDebugRegistryReclaim(t *testing.T) {
+	a := assert.New(t)
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if !a.NoError(cmd.Run()) {
+		return
+	}
+	deadPid := int32(cmd.Process.Pid)
+	r := openDebugRegistry()
+	if !a.NotNil(r) {
+		return
+	}
+	// fill the registry with the entries of a dead process.
+	r.guard.lock()
+	for i := range r.entries {
+		if e := &r.entries[i]; e.state == debugEntryFree {
+			*e = debugEntry{state: debugEntryHeld, pid: deadPid, lockID: 1}
+		}
+	}
+	r.guard.unlock()
+	defer func() {
+		r.guard.lock()
+		for i := range r.entries {
+			if e := &r.entries[i]; e.pid == deadPid {
+				e.state = debugEntryFree
+			}
+		}
+		r.guard.unlock()
+	}()
+	if !a.NoError(DestroyMutex(testDebugMutexName)) {
+		return
+	}
+	m, err := NewMutex(testDebugMutexName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer DestroyMutex(testDebugMutexName)
+	defer m.Close()
+	m.Lock()
+	_, found := findHolder(testDebugMutexName, false)
+	a.True(found)
+	m.Unlock()
+	holders, err := DumpHolders()
+	a.NoError(err)
+	for _, h := range holders {
+		a.NotEqual(int(deadPid), h.Pid)
+	}
+}
+
+func TestDebugRegistryGuardReclaim(t *testing.T) {
+	a := assert.New(t)
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if !a.NoError(cmd.Run()) {
+		return
+	}
+	r := openDebugRegistry()
+	if !a.NotNil(r) {
+		return
+	}
+	// the guard is held by a process, which has died inside a critical section.
+	r.guard.lock()
+	atomic.StoreInt32(r.guard.state, int32(cmd.Process.Pid))
+	done := make(chan error)
+	go func() {
+		_, err := DumpHolders()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		a.NoError(err)
+	case <-time.After(time.Second * 5):
+		a.Fail("the guard of a dead process was not reclaimed")
+	}
+}
+
+func TestLockDebugKinds(t *testing.T) {
+	a := assert.New(t)
+	a.Equal(newLockDebug("FutexMutex", "lock").id, newLockDebug("FutexMutex", "lock").id)
+	a.NotEqual(newLockDebug("FutexMutex", "lock").id, newLockDebug("RWMutex", "lock").id)
+	a.Equal(uint64(0), newLockDebug("FutexMutex", "").id)
+}
@@ -8,18 +8,34 @@ import (
 )
 
 // Event is a synchronization primitive used for notification.
-// If it is signaled by a call to Set(), it'll stay in this state,
+// If an auto-reset event is signaled by a call to Set(), it'll stay in this state,
 // unless someone calls Wait(). After it the event is reset into non-signaled state.
+// A manual-reset event stays signaled and releases all waiters until Reset() is called.
 type Event event
 
-// NewEvent creates a new interprocess event.
+// NewEvent creates a new interprocess auto-reset event.
 // It uses the default implementation on the current platform.
 //	name - object name.
 //	flag - flag is a combination of open flags from 'os' package.
 //	perm - object's permission bits.
 //	initial - if true, the event will be set after creation.
 func NewEvent(name string, flag int, perm os.FileMode, initial bool) (*Event, error) {
-	e, err := newEvent(name, flag, perm, initial)
+	return newEventMode(name, flag, perm, initial, false)
+}
+
+// NewManualResetEvent creates a new interprocess manual-reset event.
+// The mode is stored in the shared state, so if an existing event is opened,
+// its mode is used, whether it is a manual-reset event, or not.
+//	name - object name.
+//	flag - flag is a combination of open flags from 'os' package.
+//	perm - object's permission bits.
+//	initial - if true, the event will be set after creation.
+func NewManualResetEvent(name string, flag int, perm os.FileMode, initial bool) (*Event, error) {
+	return newEventMode(name, flag, perm, initial, true)
+}
+
+func newEventMode(name string, flag int, perm os.FileMode, initial, manualReset bool) (*Event, error) {
+	e, err := newEvent(name, flag, perm, initial, manualReset)
 	if err != nil {
 		return nil, err
 	}
@@ -27,10 +43,31 @@ func NewEvent(name string, flag int, perm os.FileMode, initial bool) (*Event, er
 }
 
 // Set sets the specified event object to the signaled state.
+// It releases one waiter of an auto-reset event, or all waiters of a manual-reset event.
 func (e *Event) Set() {
 	(*event)(e).set()
 }
 
+// Reset sets the event object to the non-signaled state.
+func (e *Event) Reset() {
+	(*event)(e).reset()
+}
+
+// Pulse releases all current waiters and leaves the event in the non-signaled state.
+func (e *Event) Pulse() {
+	(*event)(e).pulse()
+}
+
+// IsSet returns true, if the event is in the signaled state.
+func (e *Event) IsSet() bool {
+	return (*event)(e).isSet()
+}
+
+// ManualReset returns true, if the event is a manual-reset event.
+func (e *Event) ManualReset() bool {
+	return (*event)(e).manualReset()
+}
+
 // Wait waits for the event to be signaled.
 func (e *Event) Wait() {
 	(*event)(e).wait()
@@ -10,18 +10,23 @@ import (
 
 	"github.com/nxgtw/go-ipc/internal/allocator"
 	"github.com/nxgtw/go-ipc/internal/helper"
-	"bitbucket.org/avd/go-ipc/mmf"
-	"bitbucket.org/avd/go-ipc/shm"
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
 	"github.com/pkg/errors"
 )
 
+const (
+	// EventSize is the number of bytes occupied by an Event placed in a memory region.
+	EventSize = lweStateSize
+)
+
 type event struct {
 	name   string
 	region *mmf.MemoryRegion
 	lwe    *lwEvent
 }
 
-func newEvent(name string, flag int, perm os.FileMode, initial bool) (*event, error) {
+func newEvent(name string, flag int, perm os.FileMode, initial, manualReset bool) (*event, error) {
 	if err := ensureOpenFlags(flag); err != nil {
 		return nil, err
 	}
@@ -37,15 +42,59 @@ func newEvent(name string, flag int, perm os.FileMode, initial bool) (*event, er
 		region: region,
 	}
 	if created {
-		result.lwe.init(initial)
+		result.lwe.init(initial, manualReset)
 	}
 	return result, nil
 }
 
+// NewEventAt returns an auto-reset event placed in the region at the given offset.
+// Such an event does not own any named objects, so Close and Destroy do nothing,
+// and the region must not be closed while the event is in use.
+//	region - memory region, which holds the event. it must be writable.
+//	offset - offset of the event in the region. it must be 4-byte aligned.
+//	init - if true, the memory is initialized as a non-signaled event.
+func NewEventAt(region *mmf.MemoryRegion, offset int, init bool) (*Event, error) {
+	return newEventAt(region, offset, init, false)
+}
+
+// NewManualResetEventAt returns a manual-reset event placed in the region at the given offset.
+// See NewEventAt for details.
+func NewManualResetEventAt(region *mmf.MemoryRegion, offset int, init bool) (*Event, error) {
+	return newEventAt(region, offset, init, true)
+}
+
+func newEventAt(region *mmf.MemoryRegion, offset int, init, manualReset bool) (*Event, error) {
+	state, err := regionPointerAt(region, offset, EventSize, 4)
+	if err != nil {
+		return nil, err
+	}
+	result := &event{lwe: newLightweightEvent(state, &futex{ptr: state})}
+	if init {
+		result.lwe.init(false, manualReset)
+	}
+	return (*Event)(result), nil
+}
+
 func (e *event) set() {
 	e.lwe.set()
 }
 
+func (e *event) reset() {
+	e.lwe.reset()
+}
+
+func (e *event) pulse() {
+	e.lwe.pulse()
+}
+
+func (e *event) isSet() bool {
+	return e.lwe.isSet()
+}
+
+func (e *event) manualReset() bool {
+	return e.lwe.manualReset()
+}
+
 func (e *event) wait() {
 	e.waitTimeout(-1)
 }
@@ -55,10 +104,16 @@ func (e *event) waitTimeout(timeout time.Duration) bool {
 }
 
 func (e *event) close() error {
+	if e.region == nil {
+		return nil
+	}
 	return e.region.Close()
 }
 
 func (e *event) destroy() error {
+	if e.region == nil {
+		return nil
+	}
 	if err := e.close(); err != nil {
 		return errors.Wrap(err, "failed to close shm region")
 	}
@@ -0,0 +1,60 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build linux freebsd
+
+package sync
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventAt(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(shm.DestroyMemoryObject(testMemObj)) {
+		return
+	}
+	defer shm.DestroyMemoryObject(testMemObj)
+	region, err := createMemoryRegionSimple(os.O_CREATE|os.O_EXCL|os.O_RDWR, mmf.MEM_READWRITE, 64, 0)
+	if !a.NoError(err) {
+		return
+	}
+	defer region.Close()
+	ev, err := NewEventAt(region, 0, true)
+	if !a.NoError(err) {
+		return
+	}
+	mev, err := NewManualResetEventAt(region, EventSize, true)
+	if !a.NoError(err) {
+		return
+	}
+	// open existing events.
+	ev2, err := NewEventAt(region, 0, false)
+	if !a.NoError(err) {
+		return
+	}
+	mev2, err := NewEventAt(region, EventSize, false)
+	if !a.NoError(err) {
+		return
+	}
+	a.False(ev2.ManualReset())
+	a.True(mev2.ManualReset())
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		ev.Set()
+		mev.Set()
+	}()
+	a.True(ev2.WaitTimeout(time.Second))
+	a.True(mev2.WaitTimeout(time.Second))
+	a.False(ev.IsSet())
+	a.True(mev.IsSet())
+	for _, e := range []*Event{ev, mev, ev2, mev2} {
+		a.NoError(e.Destroy())
+	}
+}
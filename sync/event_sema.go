@@ -10,8 +10,8 @@ import (
 
 	"github.com/nxgtw/go-ipc/internal/allocator"
 	"github.com/nxgtw/go-ipc/internal/helper"
-	"bitbucket.org/avd/go-ipc/mmf"
-	"bitbucket.org/avd/go-ipc/shm"
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
 	"github.com/pkg/errors"
 )
 
@@ -22,7 +22,7 @@ type event struct {
 	lwe    *lwEvent
 }
 
-func newEvent(name string, flag int, perm os.FileMode, initial bool) (*event, error) {
+func newEvent(name string, flag int, perm os.FileMode, initial, manualReset bool) (*event, error) {
 	if err := ensureOpenFlags(flag); err != nil {
 		return nil, err
 	}
@@ -31,7 +31,7 @@ func newEvent(name string, flag int, perm os.FileMode, initial bool) (*event, er
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create shared state")
 	}
-	s, err := NewSemaphore(name, flag, perm, 0)
+	s, err := openSemaphore(name, flag, perm, 0)
 	if err != nil {
 		region.Close()
 		if created {
@@ -46,7 +46,7 @@ func newEvent(name string, flag int, perm os.FileMode, initial bool) (*event, er
 		s:      s,
 	}
 	if created {
-		result.lwe.init(initial)
+		result.lwe.init(initial, manualReset)
 	}
 	return result, nil
 }
@@ -55,6 +55,22 @@ func (e *event) set() {
 	e.lwe.set()
 }
 
+func (e *event) reset() {
+	e.lwe.reset()
+}
+
+func (e *event) pulse() {
+	e.lwe.pulse()
+}
+
+func (e *event) isSet() bool {
+	return e.lwe.isSet()
+}
+
+func (e *event) manualReset() bool {
+	return e.lwe.manualReset()
+}
+
 func (e *event) wait() {
 	e.waitTimeout(-1)
 }
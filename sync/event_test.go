@@ -242,6 +242,172 @@ func TestEventWait6(t *testing.T) {
 	a.True(ev.WaitTimeout(0))
 }
 
+func TestEventIsSetReset(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyEvent(testEventName)) {
+		return
+	}
+	ev, err := NewEvent(testEventName, os.O_CREATE|os.O_EXCL, 0666, true)
+	if !a.NoError(err) || !a.NotNil(ev) {
+		return
+	}
+	defer func() {
+		a.NoError(ev.Destroy())
+	}()
+	a.False(ev.ManualReset())
+	a.True(ev.IsSet())
+	ev.Reset()
+	a.False(ev.IsSet())
+	a.False(ev.WaitTimeout(0))
+	ev.Set()
+	a.True(ev.IsSet())
+	a.True(ev.WaitTimeout(0))
+	a.False(ev.IsSet())
+}
+
+func TestManualResetEvent(t *testing.T) {
+	const waiters = 16
+	a := assert.New(t)
+	if !a.NoError(DestroyEvent(testEventName)) {
+		return
+	}
+	ev, err := NewManualResetEvent(testEventName, os.O_CREATE|os.O_EXCL, 0666, false)
+	if !a.NoError(err) || !a.NotNil(ev) {
+		return
+	}
+	defer func() {
+		a.NoError(ev.Destroy())
+	}()
+	a.True(ev.ManualReset())
+	// the mode of an existing event is used.
+	ev2, err := NewEvent(testEventName, 0, 0666, false)
+	if !a.NoError(err) {
+		return
+	}
+	a.True(ev2.ManualReset())
+	a.NoError(ev2.Close())
+	var wg sync.WaitGroup
+	wg.Add(waiters)
+	for i := 0; i < waiters; i++ {
+		go func() {
+			defer wg.Done()
+			ev.Wait()
+		}()
+	}
+	time.Sleep(time.Millisecond * 50)
+	ev.Set()
+	a.True(testutil.WaitForFunc(wg.Wait, time.Second*3))
+	// the event stays signaled.
+	a.True(ev.IsSet())
+	a.True(ev.WaitTimeout(0))
+	a.True(ev.WaitTimeout(0))
+	ev.Reset()
+	a.False(ev.WaitTimeout(time.Millisecond * 50))
+}
+
+func TestManualResetEventSetReset(t *testing.T) {
+	const (
+		waiters = 4
+		rounds  = 100
+	)
+	a := assert.New(t)
+	if !a.NoError(DestroyEvent(testEventName)) {
+		return
+	}
+	ev, err := NewManualResetEvent(testEventName, os.O_CREATE|os.O_EXCL, 0666, false)
+	if !a.NoError(err) || !a.NotNil(ev) {
+		return
+	}
+	defer func() {
+		a.NoError(ev.Destroy())
+	}()
+	// waiters must be released, even though the event is reset right after it was set.
+	for i := 0; i < rounds; i++ {
+		var wg sync.WaitGroup
+		wg.Add(waiters)
+		for j := 0; j < waiters; j++ {
+			go func() {
+				defer wg.Done()
+				ev.WaitTimeout(time.Second * 3)
+			}()
+		}
+		for (*event)(ev).lwe.load().waiters() != waiters {
+			time.Sleep(time.Millisecond)
+		}
+		ev.Set()
+		ev.Reset()
+		if !a.True(testutil.WaitForFunc(wg.Wait, time.Second)) {
+			return
+		}
+	}
+	a.False(ev.IsSet())
+}
+
+func TestManualResetEventGenerationWrap(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyEvent(testEventName)) {
+		return
+	}
+	ev, err := NewManualResetEvent(testEventName, os.O_CREATE|os.O_EXCL, 0666, false)
+	if !a.NoError(err) || !a.NotNil(ev) {
+		return
+	}
+	defer func() {
+		a.NoError(ev.Destroy())
+	}()
+	lwe := (*event)(ev).lwe
+	// register as a waiter, as waitTimeout does.
+	cur, obtained := lwe.obtainOrChange(1, -1)
+	if !a.False(obtained) {
+		return
+	}
+	// let the generation wrap around to the same value.
+	for i := 0; i <= int(lweGenMask>>lweGenShift); i++ {
+		lwe.pulse()
+	}
+	a.Equal(cur.gen(), lwe.load().gen())
+	// the waiter times out. it has been released and must not be removed from waiters again.
+	_, obtained = lwe.obtainOrChange(-1, cur.gen())
+	a.True(obtained)
+	state := lwe.load()
+	a.Equal(int32(0), state.waiters())
+	a.True(state.manualReset())
+	a.False(state.signaled())
+}
+
+func TestEventPulse(t *testing.T) {
+	const waiters = 16
+	a := assert.New(t)
+	if !a.NoError(DestroyEvent(testEventName)) {
+		return
+	}
+	ev, err := NewEvent(testEventName, os.O_CREATE|os.O_EXCL, 0666, false)
+	if !a.NoError(err) || !a.NotNil(ev) {
+		return
+	}
+	defer func() {
+		a.NoError(ev.Destroy())
+	}()
+	var wg sync.WaitGroup
+	wg.Add(waiters)
+	for i := 0; i < waiters; i++ {
+		go func() {
+			defer wg.Done()
+			a.True(ev.WaitTimeout(time.Second * 3))
+		}()
+	}
+	time.Sleep(time.Millisecond * 100)
+	ev.Pulse()
+	a.True(testutil.WaitForFunc(wg.Wait, time.Second*3))
+	// pulse does not leave the event signaled.
+	a.False(ev.IsSet())
+	a.False(ev.WaitTimeout(0))
+	ev.Set()
+	ev.Pulse()
+	a.False(ev.IsSet())
+	a.False(ev.WaitTimeout(0))
+}
+
 func TestEventSetAnotherProcess(t *testing.T) {
 	a := assert.New(t)
 	if !a.NoError(DestroyEvent(testEventName)) {
@@ -0,0 +1,118 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build linux freebsd
+
+package sync
+
+import (
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/common"
+	"github.com/nxgtw/go-ipc/mmf"
+)
+
+const (
+	// EventCountSize is the number of bytes occupied by an EventCount in a memory region.
+	EventCountSize = 8
+)
+
+// EventCountKey is an epoch of an EventCount, observed by a waiter in PrepareWait.
+type EventCountKey uint32
+
+// EventCount is a futex-based notification primitive, which can be placed inside any shared memory region.
+// It allows to add blocking to lock-free data structures. A waiter does the following:
+//	key := ec.PrepareWait()
+//	if the condition is already met {
+//		ec.CancelWait()
+//	} else {
+//		ec.CommitWait(key)
+//	}
+// A notifier changes the state of the structure and calls NotifyAll.
+// A notification, that happens between PrepareWait and CommitWait, is not lost.
+// The state of an EventCount consists of two int32 cells:
+//	the first one is the epoch, which is incremented by every notification. it is also used as a futex.
+//	the second one is the number of waiters. it allows to skip wake syscall if there are no waiters.
+// Zero-filled memory is a valid initial state of an EventCount.
+type EventCount struct {
+	epoch   *futex
+	waiters *int32
+}
+
+// NewEventCount returns an EventCount placed in the region at the given offset.
+// The offset must be 4-byte aligned, and the region must have at least EventCountSize bytes after it.
+// The region must be writable and must not be closed while the EventCount is in use.
+func NewEventCount(region *mmf.MemoryRegion, offset int) (*EventCount, error) {
+	ptr, err := regionPointerAt(region, offset, EventCountSize, 4)
+	if err != nil {
+		return nil, err
+	}
+	return &EventCount{
+		epoch:   &futex{ptr: ptr},
+		waiters: (*int32)(unsafe.Pointer(uintptr(ptr) + 4)),
+	}, nil
+}
+
+// PrepareWait registers the caller as a waiter and returns current epoch.
+// After that the caller must check its condition and call either CancelWait, or CommitWait.
+func (ec *EventCount) PrepareWait() EventCountKey {
+	atomic.AddInt32(ec.waiters, 1)
+	return EventCountKey(atomic.LoadInt32(ec.epoch.addr()))
+}
+
+// CancelWait unregisters the caller, which has called PrepareWait, but does not need to wait.
+func (ec *EventCount) CancelWait() {
+	atomic.AddInt32(ec.waiters, -1)
+}
+
+// CommitWait waits until the epoch is changed by a notification.
+// If a notification has happened after PrepareWait, it returns immediately.
+func (ec *EventCount) CommitWait(key EventCountKey) {
+	if err := ec.doCommitWait(key, -1); err != nil {
+		panic(err)
+	}
+}
+
+// CommitWaitTimeout waits until the epoch is changed by a notification, but for not longer, than timeout.
+// It returns true, if there was a notification.
+func (ec *EventCount) CommitWaitTimeout(key EventCountKey, timeout time.Duration) bool {
+	err := ec.doCommitWait(key, timeout)
+	if err == nil {
+		return true
+	}
+	if common.IsTimeoutErr(err) {
+		return false
+	}
+	panic(err)
+}
+
+func (ec *EventCount) doCommitWait(key EventCountKey, timeout time.Duration) error {
+	defer atomic.AddInt32(ec.waiters, -1)
+	var err error
+	common.CallTimeout(func(curTimeout time.Duration) bool {
+		if EventCountKey(atomic.LoadInt32(ec.epoch.addr())) != key {
+			return false
+		}
+		if err = ec.epoch.wait(int32(key), curTimeout); err != nil {
+			return false
+		}
+		// if the epoch has not changed, this was a spurious wakeup.
+		return EventCountKey(atomic.LoadInt32(ec.epoch.addr())) == key
+	}, timeout)
+	if err == nil && EventCountKey(atomic.LoadInt32(ec.epoch.addr())) == key {
+		err = common.NewTimeoutError("FUTEX")
+	}
+	return err
+}
+
+// NotifyAll wakes all waiters, which have called PrepareWait before it.
+func (ec *EventCount) NotifyAll() {
+	ec.epoch.add(1)
+	if atomic.LoadInt32(ec.waiters) == 0 {
+		return
+	}
+	if _, err := ec.epoch.wakeAll(); err != nil {
+		panic(err)
+	}
+}
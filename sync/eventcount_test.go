@@ -0,0 +1,112 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build linux freebsd
+
+package sync
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	testutil "github.com/nxgtw/go-ipc/internal/test"
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventCountOffset(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(shm.DestroyMemoryObject(testMemObj)) {
+		return
+	}
+	defer shm.DestroyMemoryObject(testMemObj)
+	region, err := createMemoryRegionSimple(os.O_CREATE|os.O_EXCL|os.O_RDWR, mmf.MEM_READWRITE, 64, 0)
+	if !a.NoError(err) {
+		return
+	}
+	defer region.Close()
+	_, err = NewEventCount(region, 2)
+	a.Error(err)
+	_, err = NewEventCount(region, 64-EventCountSize+4)
+	a.Error(err)
+	_, err = NewEventCount(region, -4)
+	a.Error(err)
+	_, err = NewEventCount(region, 64-EventCountSize)
+	a.NoError(err)
+}
+
+func TestEventCount(t *testing.T) {
+	const (
+		waiters = 8
+		rounds  = 100
+	)
+	a := assert.New(t)
+	if !a.NoError(shm.DestroyMemoryObject(testMemObj)) {
+		return
+	}
+	defer shm.DestroyMemoryObject(testMemObj)
+	region, err := createMemoryRegionSimple(os.O_CREATE|os.O_EXCL|os.O_RDWR, mmf.MEM_READWRITE, 64, 0)
+	if !a.NoError(err) {
+		return
+	}
+	defer region.Close()
+	ec, err := NewEventCount(region, 8)
+	if !a.NoError(err) {
+		return
+	}
+	// value is a lock-free counter, waiters wait for it to reach every next round.
+	var value int32
+	var wg sync.WaitGroup
+	wg.Add(waiters)
+	for i := 0; i < waiters; i++ {
+		go func() {
+			defer wg.Done()
+			for round := int32(1); round <= rounds; round++ {
+				for {
+					key := ec.PrepareWait()
+					if atomic.LoadInt32(&value) >= round {
+						ec.CancelWait()
+						break
+					}
+					ec.CommitWait(key)
+				}
+			}
+		}()
+	}
+	for round := 0; round < rounds; round++ {
+		atomic.AddInt32(&value, 1)
+		ec.NotifyAll()
+	}
+	a.True(testutil.WaitForFunc(wg.Wait, time.Second*3))
+	a.Equal(int32(0), *ec.waiters)
+}
+
+func TestEventCountTimeout(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(shm.DestroyMemoryObject(testMemObj)) {
+		return
+	}
+	defer shm.DestroyMemoryObject(testMemObj)
+	region, err := createMemoryRegionSimple(os.O_CREATE|os.O_EXCL|os.O_RDWR, mmf.MEM_READWRITE, 64, 0)
+	if !a.NoError(err) {
+		return
+	}
+	defer region.Close()
+	ec, err := NewEventCount(region, 0)
+	if !a.NoError(err) {
+		return
+	}
+	key := ec.PrepareWait()
+	start := time.Now()
+	a.False(ec.CommitWaitTimeout(key, time.Millisecond*50))
+	a.True(time.Since(start) >= time.Millisecond*50)
+	// a notification between PrepareWait and CommitWait is not lost.
+	key = ec.PrepareWait()
+	ec.NotifyAll()
+	a.True(ec.CommitWaitTimeout(key, 0))
+	a.Equal(int32(0), *ec.waiters)
+}
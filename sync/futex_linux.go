@@ -19,6 +19,13 @@ const (
 	cFUTEX_REQUEUE     = 3
 	cFUTEX_CMP_REQUEUE = 4
 	cFUTEX_WAKE_OP     = 5
+	cFUTEX_LOCK_PI     = 6
+	cFUTEX_UNLOCK_PI   = 7
+	cFUTEX_TRYLOCK_PI  = 8
+
+	cFUTEX_WAITERS    = 0x80000000
+	cFUTEX_OWNER_DIED = 0x40000000
+	cFUTEX_TID_MASK   = 0x3fffffff
 
 	// FUTEX_PRIVATE_FLAG is used to optimize futex usage for process-private futexes.
 	FUTEX_PRIVATE_FLAG = 128
@@ -62,6 +69,52 @@ func FutexWake(addr unsafe.Pointer, count int32, flags int32) (int, error) {
 	return 0, err
 }
 
+// FutexCmpRequeue checks if the value equals futex's value.
+// If it doesn't, FutexCmpRequeue returns EWOULDBLOCK.
+// Otherwise, it wakes up to wake waiters of the futex, and moves up to requeue
+// of the remaining waiters to the futex at addr2.
+// Returns the total number of woken and requeued waiters.
+func FutexCmpRequeue(addr unsafe.Pointer, value int32, wake, requeue int32, addr2 unsafe.Pointer, flags int32) (int, error) {
+	var count int32
+	err := common.UninterruptedSyscall(func() error {
+		var err error
+		count, err = sys_futex_requeue(addr, cFUTEX_CMP_REQUEUE|flags, wake, requeue, addr2, uint32(value))
+		return err
+	})
+	if err == nil {
+		return int(count), nil
+	}
+	return 0, err
+}
+
+// FutexLockPI locks a priority-inheritance futex, waiting for not longer, than timeout.
+// The futex value must be 0, if it is unlocked, or contain the TID of the owner thread.
+// The timeout is measured against CLOCK_REALTIME.
+func FutexLockPI(addr unsafe.Pointer, timeout time.Duration, flags int32) error {
+	return common.UninterruptedSyscallTimeout(func(tm time.Duration) error {
+		_, err := sys_futex(addr, cFUTEX_LOCK_PI|flags, 0, unsafe.Pointer(common.AbsTimeoutToTimeSpec(tm)), nil, 0)
+		return err
+	}, timeout)
+}
+
+// FutexTryLockPI makes one attempt to lock a priority-inheritance futex.
+// If the futex is locked, it returns EWOULDBLOCK.
+func FutexTryLockPI(addr unsafe.Pointer, flags int32) error {
+	return common.UninterruptedSyscall(func() error {
+		_, err := sys_futex(addr, cFUTEX_TRYLOCK_PI|flags, 0, nil, nil, 0)
+		return err
+	})
+}
+
+// FutexUnlockPI unlocks a priority-inheritance futex, owned by the calling thread,
+// and wakes the top priority waiter.
+func FutexUnlockPI(addr unsafe.Pointer, flags int32) error {
+	return common.UninterruptedSyscall(func() error {
+		_, err := sys_futex(addr, cFUTEX_UNLOCK_PI|flags, 0, nil, nil, 0)
+		return err
+	})
+}
+
 func sys_futex(addr unsafe.Pointer, op int32, val int32, ts, addr2 unsafe.Pointer, val3 uint32) (int32, error) {
 	r1, _, err := unix.Syscall6(unix.SYS_FUTEX,
 		uintptr(addr),
@@ -72,6 +125,25 @@ func sys_futex(addr unsafe.Pointer, op int32, val int32, ts, addr2 unsafe.Pointe
 		uintptr(val3))
 	allocator.Use(addr)
 	allocator.Use(addr2)
+	return futexResult(r1, err)
+}
+
+// sys_futex_requeue is the same as sys_futex, but it passes the number of waiters
+// to requeue instead of the timeout.
+func sys_futex_requeue(addr unsafe.Pointer, op int32, val int32, val2 int32, addr2 unsafe.Pointer, val3 uint32) (int32, error) {
+	r1, _, err := unix.Syscall6(unix.SYS_FUTEX,
+		uintptr(addr),
+		uintptr(op),
+		uintptr(val),
+		uintptr(val2),
+		uintptr(addr2),
+		uintptr(val3))
+	allocator.Use(addr)
+	allocator.Use(addr2)
+	return futexResult(r1, err)
+}
+
+func futexResult(r1 uintptr, err unix.Errno) (int32, error) {
 	switch err {
 	case 0:
 		return int32(r1), nil
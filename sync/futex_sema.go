@@ -0,0 +1,89 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build linux
+
+package sync
+
+import (
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/common"
+)
+
+const (
+	futexSemaStateSize = 8
+)
+
+// futexSema is a counting semaphore operating on two int32 memory cells:
+//	the first one is the value of the semaphore, it is also used as a futex.
+//	the second one is the number of waiters. it allows to skip wake syscall if there are no waiters.
+// futexSema implements waitWaker interface, where wake increments the value,
+// and wait decrements it, blocking while it is zero.
+type futexSema struct {
+	value   *futex
+	waiters *int32
+}
+
+func newFutexSema(state unsafe.Pointer) *futexSema {
+	return &futexSema{
+		value:   &futex{ptr: state},
+		waiters: (*int32)(unsafe.Pointer(uintptr(state) + 4)),
+	}
+}
+
+// init writes initial values into semaphore's memory location.
+func (fs *futexSema) init(value int32) {
+	*fs.value.addr() = value
+	*fs.waiters = 0
+}
+
+// tryWait decrements the value, if it is positive. It returns true on success.
+func (fs *futexSema) tryWait() bool {
+	for {
+		old := atomic.LoadInt32(fs.value.addr())
+		if old <= 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(fs.value.addr(), old, old-1) {
+			return true
+		}
+	}
+}
+
+// wake increments the value of the semaphore by count and wakes up to count waiters.
+func (fs *futexSema) wake(count int32) (int, error) {
+	atomic.AddInt32(fs.value.addr(), count)
+	if atomic.LoadInt32(fs.waiters) == 0 {
+		return 0, nil
+	}
+	return fs.value.wake(count)
+}
+
+// wait decrements the value of the semaphore.
+// If the value is zero, it waits for not longer, than timeout for it to become positive.
+func (fs *futexSema) wait(unused int32, timeout time.Duration) error {
+	if fs.tryWait() {
+		return nil
+	}
+	var err error
+	var obtained bool
+	common.CallTimeout(func(curTimeout time.Duration) bool {
+		atomic.AddInt32(fs.waiters, 1)
+		err = fs.value.wait(0, curTimeout)
+		atomic.AddInt32(fs.waiters, -1)
+		if err != nil {
+			return false
+		}
+		obtained = fs.tryWait()
+		return !obtained
+	}, timeout)
+	if obtained {
+		return nil
+	}
+	if err == nil {
+		err = common.NewTimeoutError("FUTEX")
+	}
+	return err
+}
@@ -0,0 +1,57 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build linux
+
+package sync
+
+import (
+	"sync"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFutexSemaCount(t *testing.T) {
+	const (
+		waiters = 16
+		rounds  = 1000
+	)
+	a := assert.New(t)
+	var state [futexSemaStateSize / 4]int32
+	fs := newFutexSema(unsafe.Pointer(&state[0]))
+	fs.init(0)
+	var wg sync.WaitGroup
+	wg.Add(waiters)
+	for i := 0; i < waiters; i++ {
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				a.NoError(fs.wait(0, -1))
+			}
+		}()
+	}
+	for i := 0; i < waiters*rounds; i++ {
+		_, err := fs.wake(1)
+		a.NoError(err)
+	}
+	wg.Wait()
+	a.Equal(int32(0), *fs.value.addr())
+	a.Equal(int32(0), *fs.waiters)
+}
+
+func TestFutexSemaTimeout(t *testing.T) {
+	a := assert.New(t)
+	var state [futexSemaStateSize / 4]int32
+	fs := newFutexSema(unsafe.Pointer(&state[0]))
+	fs.init(1)
+	a.NoError(fs.wait(0, 0))
+	start := time.Now()
+	err := fs.wait(0, time.Millisecond*50)
+	a.True(common.IsTimeoutErr(err))
+	a.True(time.Since(start) >= time.Millisecond*50)
+	a.Equal(int32(0), *fs.waiters)
+}
@@ -0,0 +1,7 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build linux,sysv_sema_linux
+
+package sync
+
+var helperBuildTags = "sysv_sema_linux"
@@ -0,0 +1,9 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build !linux !sysv_sema_linux
+
+package sync
+
+// helperBuildTags are passed to 'go run', when test helpers are started,
+// so that they use the same implementation of the primitives, as the tests do.
+var helperBuildTags = ""
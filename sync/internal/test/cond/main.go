@@ -6,9 +6,10 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
-	"bitbucket.org/avd/go-ipc/sync"
+	"github.com/nxgtw/go-ipc/sync"
 )
 
 var (
@@ -21,6 +22,7 @@ available commands:
   wait cond_name locker_name
   signal cond_name
   broadcast cond_name
+  waitn ready_sema_name cond_name locker_name n
 `
 
 func makeCond(condName, lockerName string) (cond *sync.Cond, l sync.IPCLocker, err error) {
@@ -62,6 +64,39 @@ func wait() error {
 	return nil
 }
 
+// waitn locks the mutex and waits on the condvar n times.
+// it signals the semaphore, when it has locked the mutex for the first time.
+func waitn() error {
+	if flag.NArg() != 5 {
+		return fmt.Errorf("waitn: must provide sema, cond and locker names, and the number of waits")
+	}
+	n, err := strconv.Atoi(flag.Arg(4))
+	if err != nil {
+		return err
+	}
+	s, err := sync.NewSemaphore(flag.Arg(1), 0, 0666, 0)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	cond, l, err := makeCond(flag.Arg(2), flag.Arg(3))
+	if err != nil {
+		return err
+	}
+	l.Lock()
+	s.Signal(1)
+	for i := 0; i < n; i++ {
+		cond.Wait()
+	}
+	l.Unlock()
+	if err1, err2 := cond.Close(), l.Close(); err1 != nil {
+		return err1
+	} else if err2 != nil {
+		return err2
+	}
+	return nil
+}
+
 func signal() error {
 	if flag.NArg() != 2 {
 		return fmt.Errorf("signal: must provide cond name only")
@@ -97,6 +132,8 @@ func runCommand() error {
 		return signal()
 	case "broadcast":
 		return broadcast()
+	case "waitn":
+		return waitn()
 	default:
 		return fmt.Errorf("unknown command")
 	}
@@ -8,7 +8,7 @@ import (
 	"os"
 	"time"
 
-	"bitbucket.org/avd/go-ipc/sync"
+	"github.com/nxgtw/go-ipc/sync"
 )
 
 var (
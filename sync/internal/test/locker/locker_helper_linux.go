@@ -0,0 +1,29 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	ipc_sync "github.com/nxgtw/go-ipc/sync"
+)
+
+func createPlatformLocker(typ, name string, flag int) (locker sync.Locker, err error) {
+	switch typ {
+	case "pi":
+		locker, err = ipc_sync.NewPIMutex(name, flag, 0666)
+	default:
+		err = fmt.Errorf("unknown object type %q", typ)
+	}
+	return
+}
+
+func destroyPlatformLocker(typ, name string) error {
+	switch typ {
+	case "pi":
+		return ipc_sync.DestroyPIMutex(name)
+	default:
+		return fmt.Errorf("unknown object type %q", typ)
+	}
+}
@@ -5,10 +5,9 @@
 package main
 
 import (
-	"fmt"
 	"sync"
 
-	ipc_sync "bitbucket.org/avd/go-ipc/sync"
+	ipc_sync "github.com/nxgtw/go-ipc/sync"
 )
 
 func createLocker(typ, name string, flag int) (locker sync.Locker, err error) {
@@ -21,8 +20,10 @@ func createLocker(typ, name string, flag int) (locker sync.Locker, err error) {
 		locker, err = ipc_sync.NewSpinMutex(name, flag, 0666)
 	case "rw":
 		locker, err = ipc_sync.NewRWMutex(name, flag, 0666)
+	case "rec":
+		locker, err = ipc_sync.NewRecursiveMutex(name, flag, 0666)
 	default:
-		err = fmt.Errorf("unknown object type %q", typ)
+		locker, err = createPlatformLocker(typ, name, flag)
 	}
 	return
 }
@@ -37,7 +38,9 @@ func destroyLocker(typ, name string) error {
 		return ipc_sync.DestroySpinMutex(name)
 	case "rw":
 		return ipc_sync.DestroyRWMutex(name)
+	case "rec":
+		return ipc_sync.DestroyRecursiveMutex(name)
 	default:
-		return fmt.Errorf("unknown object type %q", typ)
+		return destroyPlatformLocker(typ, name)
 	}
 }
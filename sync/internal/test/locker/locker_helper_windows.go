@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"sync"
 
-	ipc_sync "bitbucket.org/avd/go-ipc/sync"
+	ipc_sync "github.com/nxgtw/go-ipc/sync"
 )
 
 func createLocker(typ, name string, mode int) (locker sync.Locker, err error) {
@@ -17,6 +17,8 @@ func createLocker(typ, name string, mode int) (locker sync.Locker, err error) {
 		locker, err = ipc_sync.NewSpinMutex(name, mode, 0666)
 	case "rw":
 		locker, err = ipc_sync.NewRWMutex(name, mode, 0666)
+	case "rec":
+		locker, err = ipc_sync.NewRecursiveMutex(name, mode, 0666)
 	default:
 		err = fmt.Errorf("unknown object type %q", typ)
 	}
@@ -31,6 +33,8 @@ func destroyLocker(typ, name string) error {
 		return ipc_sync.DestroySpinMutex(name)
 	case "rw":
 		return ipc_sync.DestroyRWMutex(name)
+	case "rec":
+		return ipc_sync.DestroyRecursiveMutex(name)
 	default:
 		return fmt.Errorf("unknown object type %q", typ)
 	}
@@ -12,9 +12,9 @@ import (
 
 	"github.com/nxgtw/go-ipc/internal/allocator"
 	testutil "github.com/nxgtw/go-ipc/internal/test"
-	"bitbucket.org/avd/go-ipc/mmf"
-	"bitbucket.org/avd/go-ipc/shm"
-	ipc_sync "bitbucket.org/avd/go-ipc/sync"
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
+	ipc_sync "github.com/nxgtw/go-ipc/sync"
 )
 
 var (
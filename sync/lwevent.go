@@ -13,14 +13,61 @@ import (
 
 const (
 	lweStateSize = 4
+
+	lweSignaled    = int32(math.MinInt32)
+	lweManualReset = int32(1) << 30
+	lweGenShift    = 14
+	lweGenMask     = int32(0xFFFF) << lweGenShift
+	lweWaitersMask = int32(0x3FFF)
 )
 
+// lweState is a shared event state with the following bits distribution:
+//	|    31    |      30      |29..........14|13..........0|
+//	| signaled | manual reset |  generation  |   waiters   |
+// manual reset bit is set once, when the event is created, and never changes.
+// generation is incremented by every pulse, and by every set of a manual-reset event,
+// so that released waiters could know it, even if the event has already been reset.
+type lweState int32
+
+func (s lweState) signaled() bool {
+	return int32(s)&lweSignaled != 0
+}
+
+func (s lweState) manualReset() bool {
+	return int32(s)&lweManualReset != 0
+}
+
+func (s lweState) gen() int32 {
+	return int32(s) & lweGenMask
+}
+
+func (s lweState) waiters() int32 {
+	return int32(s) & lweWaitersMask
+}
+
+func (s *lweState) setSignaled(signaled bool) {
+	if signaled {
+		*(*int32)(s) |= lweSignaled
+	} else {
+		*(*int32)(s) &= ^lweSignaled
+	}
+}
+
+func (s *lweState) addWaiters(count int32) {
+	*(*int32)(s) += count
+}
+
+// nextGen increments generation and removes all waiters.
+func (s *lweState) nextGen() {
+	gen := (s.gen() + (1 << lweGenShift)) & lweGenMask
+	*(*int32)(s) = int32(*s) & ^(lweGenMask|lweWaitersMask) | gen
+}
+
 // lwEvent is a lightweight event implementation operating on a uint32 memory cell.
 // it tries to minimize amount of syscalls.
 // actual wait/wake must be implemented by a waitWaker object.
-// state is a shared variable, that contains event state:
-//	the highest bit is a signal bit
-//	all other bits define the number of waiters.
+// an auto-reset event releases one waiter and becomes non-signaled,
+// a manual-reset event releases all waiters and stays signaled until reset.
 type lwEvent struct {
 	state *int32
 	ww    waitWaker
@@ -30,69 +77,138 @@ func newLightweightEvent(state unsafe.Pointer, ww waitWaker) *lwEvent {
 	return &lwEvent{state: (*int32)(state), ww: ww}
 }
 
-func (e *lwEvent) init(set bool) {
+func (e *lwEvent) init(set, manualReset bool) {
 	val := int32(0)
 	if set {
-		val = math.MinInt32
+		val |= lweSignaled
+	}
+	if manualReset {
+		val |= lweManualReset
 	}
 	*e.state = val
 }
 
+func (e *lwEvent) load() lweState {
+	return lweState(atomic.LoadInt32(e.state))
+}
+
+func (e *lwEvent) cas(old, new lweState) bool {
+	return atomic.CompareAndSwapInt32(e.state, int32(old), int32(new))
+}
+
+func (e *lwEvent) isSet() bool {
+	return e.load().signaled()
+}
+
+func (e *lwEvent) manualReset() bool {
+	return e.load().manualReset()
+}
+
 func (e *lwEvent) set() {
-	var old int32
+	var old lweState
+	for {
+		old = e.load()
+		if old.signaled() {
+			return
+		}
+		new := old
+		new.setSignaled(true)
+		if old.manualReset() {
+			// all waiters are released, even if the event is reset before they check it.
+			new.nextGen()
+		}
+		if e.cas(old, new) {
+			break
+		}
+	}
+	if waiters := old.waiters(); waiters > 0 {
+		if !old.manualReset() {
+			waiters = 1
+		}
+		e.ww.wake(waiters)
+	}
+}
+
+func (e *lwEvent) reset() {
 	for {
-		old = atomic.LoadInt32(e.state)
-		if old < 0 {
+		old := e.load()
+		if !old.signaled() {
+			return
+		}
+		new := old
+		new.setSignaled(false)
+		if e.cas(old, new) {
 			return
 		}
-		new := old | math.MinInt32
-		if atomic.CompareAndSwapInt32(e.state, old, new) {
+	}
+}
+
+// pulse releases all current waiters and leaves the event non-signaled.
+func (e *lwEvent) pulse() {
+	var old lweState
+	for {
+		old = e.load()
+		new := old
+		new.setSignaled(false)
+		new.nextGen()
+		if e.cas(old, new) {
 			break
 		}
 	}
-	if old > 0 {
-		e.ww.wake(1)
+	if waiters := old.waiters(); waiters > 0 {
+		e.ww.wake(waiters)
 	}
 }
 
-func (e *lwEvent) obtainOrChange(inc int32) (new int32, obtained bool) {
+// obtainOrChange tries to obtain the event. if the event is not signaled, it changes the number of waiters by inc.
+// gen is the generation, observed by a registered waiter, or -1, if the caller is not a waiter.
+// it returns observed state and true, if the event was obtained.
+func (e *lwEvent) obtainOrChange(inc int32, gen int32) (lweState, bool) {
 	for {
-		old := atomic.LoadInt32(e.state)
-		new = old
-		if old < 0 { // reset 'set' bit
-			new = old & ^math.MinInt32
-		} else { // change the value
+		old := e.load()
+		if gen >= 0 && (old.gen() != gen || old.waiters() == 0) {
+			// we have been released by a pulse or a set, which has already removed us from waiters.
+			// if there are no waiters, the generation has wrapped around, and we are not registered anymore.
+			return old, true
+		}
+		new := old
+		if old.signaled() {
+			if !old.manualReset() {
+				new.setSignaled(false)
+			}
+			if gen >= 0 {
+				new.addWaiters(-1)
+			}
+		} else {
 			if inc == 0 {
-				return
+				return old, false
 			}
-			new = old + inc
+			new.addWaiters(inc)
 		}
-		if atomic.CompareAndSwapInt32(e.state, old, new) {
-			if old < 0 { // bit was set and we reset it. success. otherwise, we changed the value.
-				obtained = true
-			}
-			return
+		if e.cas(old, new) {
+			return new, old.signaled()
 		}
 	}
 }
 
 func (e *lwEvent) waitTimeout(timeout time.Duration) bool {
 	// first, we are trying to catch the event, or add us as a waiter.
-	new, obtained := e.obtainOrChange(1)
+	cur, obtained := e.obtainOrChange(1, -1)
 	if obtained {
 		return true
 	}
+	gen := cur.gen()
 	// in the loop we wait for the value to change and then observe new value:
 	//	if it is still not set, wait again
 	//	otherwise, try to obtain the event.
 	for {
-		if err := e.ww.wait(new, timeout); err != nil {
+		if err := e.ww.wait(int32(cur), timeout); err != nil {
 			if common.IsTimeoutErr(err) {
-				_, obtained = e.obtainOrChange(-1)
+				_, obtained = e.obtainOrChange(-1, gen)
 				return obtained
 			}
 		}
-		new, obtained = e.obtainOrChange(0)
+		cur, obtained = e.obtainOrChange(0, gen)
 		if obtained {
 			return true
 		}
@@ -25,6 +25,8 @@ const (
 type lwMutex struct {
 	state *int32
 	ww    waitWaker
+	dbg   lockDebug
+	stats *statsRecorder
 }
 
 func newLightweightMutex(state unsafe.Pointer, ww waitWaker) *lwMutex {
@@ -43,7 +45,17 @@ func (lwm *lwMutex) lock() {
 }
 
 func (lwm *lwMutex) tryLock() bool {
-	return atomic.CompareAndSwapInt32(lwm.state, lwmUnlocked, lwmLockedNoWaiters)
+	if atomic.CompareAndSwapInt32(lwm.state, lwmUnlocked, lwmLockedNoWaiters) {
+		lwm.stats.acquired()
+		lwm.dbg.acquired()
+		return true
+	}
+	return false
+}
+
+// locked returns true, if the mutex is held by someone. it doesn't change the state.
+func (lwm *lwMutex) locked() bool {
+	return atomic.LoadInt32(lwm.state) != lwmUnlocked
 }
 
 func (lwm *lwMutex) lockTimeout(timeout time.Duration) bool {
@@ -63,20 +75,42 @@ func (lwm *lwMutex) doLock(timeout time.Duration) error {
 			return nil
 		}
 	}
+	lwm.dbg.wait()
+	start := lwm.stats.start()
 	old := atomic.LoadInt32(lwm.state)
 	if old != lwmLockedHaveWaiters {
 		old = atomic.SwapInt32(lwm.state, lwmLockedHaveWaiters)
 	}
 	for old != lwmUnlocked {
 		if err := lwm.ww.wait(lwmLockedHaveWaiters, timeout); err != nil {
+			lwm.stats.waited(start, false)
+			lwm.dbg.cancel()
 			return err
 		}
 		old = atomic.SwapInt32(lwm.state, lwmLockedHaveWaiters)
 	}
+	lwm.stats.waited(start, true)
+	lwm.dbg.acquired()
 	return nil
 }
 
+// lockContended locks the mutex, assuming, that there are other waiters.
+// it is used by waiters, which might have been moved to the mutex futex by a condvar,
+// so that every unlock wakes the next waiter.
+func (lwm *lwMutex) lockContended() {
+	lwm.dbg.wait()
+	start := lwm.stats.start()
+	for atomic.SwapInt32(lwm.state, lwmLockedHaveWaiters) != lwmUnlocked {
+		if err := lwm.ww.wait(lwmLockedHaveWaiters, -1); err != nil {
+			panic(err)
+		}
+	}
+	lwm.stats.waited(start, true)
+	lwm.dbg.acquired()
+}
+
 func (lwm *lwMutex) unlock() {
+	lwm.dbg.released()
 	if old := atomic.LoadInt32(lwm.state); old == lwmLockedHaveWaiters {
 		*lwm.state = lwmUnlocked
 	} else {
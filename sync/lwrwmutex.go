@@ -4,21 +4,33 @@ package sync
 
 import (
 	"sync/atomic"
+	"time"
 	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/common"
 )
 
 const (
-	lwRWMStateSize          = 8
-	lwRWMMask               = 0x1FFFFF
-	lwRWMWaitingReaderShift = 21
-	lwRWMWriterShift        = 42
+	lwRWMStateSize          = 16
+	lwRWMGateOffset         = 12
+	lwRWMMask               = 0xFFFFF
+	lwRWMWaitingReaderShift = 20
+	lwRWMWriterShift        = 40
+	lwRWMWriterGranted      = int64(1) << 60
+	lwRWMUpgradable         = int64(1) << 61
+	lwRWMUpgrading          = int64(1) << 62
 )
 
-// wRWState is a shared rwmutex state with the following bits distribution:
-//  ...63...|62.................42|41.................21|20.................0|
-//  --------|---------------------|---------------------|--------------------|
-//   unused |       writers       |   waiting readers   |      readers       |
-// which gives us up to 2kk readers and writers.
+// lwRWState is a shared rwmutex state with the following bits distribution:
+//  ...63...|    62     |    61     |    60     |59...............40|39...............20|19................0|
+//  --------|-----------|-----------|-----------|-------------------|-------------------|-------------------|
+//   unused | upgrading | upgradable| w.granted |      writers      |  waiting readers  |      readers      |
+// which gives us up to 1kk readers and writers.
+// writers is the number of writers, which hold the lock or wait for it.
+// w.granted is set, when the lock has been handed over to one of the waiting writers,
+// and that writer has not woken up yet.
+// upgradable is set, if one of the readers holds the lock in upgradable mode.
+// upgrading is set, if that reader waits for other readers to release the lock to become a writer.
 type lwRWState int64
 
 func (s lwRWState) readers() int64 {
@@ -33,6 +45,18 @@ func (s lwRWState) writers() int64 {
 	return ((int64)(s) >> lwRWMWriterShift) & lwRWMMask
 }
 
+func (s lwRWState) writerGranted() bool {
+	return (int64)(s)&lwRWMWriterGranted != 0
+}
+
+func (s lwRWState) upgradable() bool {
+	return (int64)(s)&lwRWMUpgradable != 0
+}
+
+func (s lwRWState) upgrading() bool {
+	return (int64)(s)&lwRWMUpgrading != 0
+}
+
 func (s *lwRWState) addReaders(count int64) {
 	*(*int64)(s) += count
 }
@@ -45,86 +69,443 @@ func (s *lwRWState) addWriters(count int64) {
 	*(*int64)(s) += count << lwRWMWriterShift
 }
 
+func (s *lwRWState) setWriterGranted(granted bool) {
+	if granted {
+		*(*int64)(s) |= lwRWMWriterGranted
+	} else {
+		*(*int64)(s) &= ^lwRWMWriterGranted
+	}
+}
+
+func (s *lwRWState) setUpgradable(upgradable bool) {
+	if upgradable {
+		*(*int64)(s) |= lwRWMUpgradable
+	} else {
+		*(*int64)(s) &= ^(lwRWMUpgradable | lwRWMUpgrading)
+	}
+}
+
+func (s *lwRWState) setUpgrading() {
+	*(*int64)(s) |= lwRWMUpgrading
+}
+
+// rwmWaiters is a set of waitWakers used by lwRWMutex:
+//	r - for readers.
+//	w - for writers.
+//	u - for an upgrading reader.
+//	g - for the gate mutex of upgradable lockers.
+type rwmWaiters struct {
+	r, w, u, g waitWaker
+}
+
 // lwRWMutex is an optimized low-level rwmutex implementation,
 // that doesn't have internal lock for its state.
 // this implementation is inspired by Jeff Preshing and his article at
 // http://preshing.com/20150316/semaphores-are-surprisingly-versatile/
 // and his c++ implementation (github.com/preshing/cpp11-on-multicore).
+// the lock is handed over to waiting readers or writers according to the policy,
+// which is stored in the shared memory right after the state.
+// waiters, that have timed out, remove themselves from the state, unless the lock
+// has already been handed over to them. in this case they take the lock.
+// upgradable lockers are serialized by the gate mutex, which is stored
+// in the shared memory right after the policy. an upgradable locker holds the gate
+// and a read lock. when it upgrades, the last leaving reader hands the lock over to it,
+// so that no writer can acquire the lock in meanwhile.
 type lwRWMutex struct {
 	rWaiter waitWaker
 	wWaiter waitWaker
+	uWaiter waitWaker
 	state   *int64
+	policy  *int32
+	gate    *lwMutex
+	stats   *statsRecorder
 }
 
-func newRWLightweightMutex(state unsafe.Pointer, rWaiter, wWaiter waitWaker) *lwRWMutex {
-	return &lwRWMutex{state: (*int64)(state), rWaiter: rWaiter, wWaiter: wWaiter}
+func newRWLightweightMutex(state unsafe.Pointer, waiters rwmWaiters) *lwRWMutex {
+	return &lwRWMutex{
+		state:   (*int64)(state),
+		policy:  (*int32)(unsafe.Pointer(uintptr(state) + 8)),
+		gate:    newLightweightMutex(unsafe.Pointer(uintptr(state)+lwRWMGateOffset), waiters.g),
+		rWaiter: waiters.r,
+		wWaiter: waiters.w,
+		uWaiter: waiters.u,
+	}
 }
 
 // init writes initial value into mutex's memory location.
-func (lwrw *lwRWMutex) init() {
+func (lwrw *lwRWMutex) init(policy RWMutexPolicy) {
 	*lwrw.state = 0
+	*lwrw.policy = int32(policy)
+	lwrw.gate.init()
+}
+
+func (lwrw *lwRWMutex) getPolicy() RWMutexPolicy {
+	return RWMutexPolicy(atomic.LoadInt32(lwrw.policy))
+}
+
+func (lwrw *lwRWMutex) load() lwRWState {
+	return lwRWState(atomic.LoadInt64(lwrw.state))
+}
+
+// readerMustWait returns true, if a reader must wait for the lock in the given state.
+func (lwrw *lwRWMutex) readerMustWait(s lwRWState) bool {
+	if s.upgrading() {
+		// do not let new readers in, as the upgrading reader waits for others to leave.
+		return true
+	}
+	if lwrw.getPolicy() == RWMutexReaderPreferring {
+		// readers wait only if the lock is held by, or has been handed over to a writer.
+		return s.writers() > 0 && s.readers() == 0
+	}
+	// readers wait, if there are active or waiting writers.
+	return s.writers() > 0
+}
+
+// handOver is called after a writer has released the lock.
+// It passes the lock to waiting readers, or to one of the waiting writers according to the policy.
+// It returns the number of readers to wake and a flag, whether a writer must be woken.
+func (lwrw *lwRWMutex) handOver(s *lwRWState) (int64, bool) {
+	wr := s.waitingReaders()
+	if wr > 0 && (s.writers() == 0 || lwrw.getPolicy() != RWMutexWriterPreferring) {
+		s.addWaitingReaders(-wr)
+		s.addReaders(wr)
+		return wr, false
+	}
+	if s.writers() > 0 {
+		s.setWriterGranted(true)
+		return 0, true
+	}
+	return 0, false
 }
 
 func (lwrw *lwRWMutex) lock() {
+	if err := lwrw.doLock(-1); err != nil {
+		panic(err)
+	}
+}
+
+func (lwrw *lwRWMutex) lockTimeout(timeout time.Duration) bool {
+	err := lwrw.doLock(timeout)
+	if err == nil {
+		return true
+	}
+	if common.IsTimeoutErr(err) {
+		return false
+	}
+	panic(err)
+}
+
+func (lwrw *lwRWMutex) tryLock() bool {
+	for {
+		old := (lwRWState)(atomic.LoadInt64(lwrw.state))
+		if old.readers() > 0 || old.writers() > 0 {
+			return false
+		}
+		new := old
+		new.addWriters(1)
+		if atomic.CompareAndSwapInt64(lwrw.state, (int64)(old), (int64)(new)) {
+			lwrw.stats.acquired()
+			return true
+		}
+	}
+}
+
+func (lwrw *lwRWMutex) doLock(timeout time.Duration) error {
 	new := (lwRWState)(atomic.AddInt64(lwrw.state, 1<<lwRWMWriterShift))
-	if new.readers() > 0 || new.writers() > 1 {
-		if err := lwrw.wWaiter.wait(0, -1); err != nil {
-			panic(err)
+	if new.readers() == 0 && new.writers() == 1 {
+		lwrw.stats.acquired()
+		return nil
+	}
+	start := lwrw.stats.start()
+	err := lwrw.wWaiter.wait(0, timeout)
+	if err == nil {
+		lwrw.takeGrant()
+		lwrw.stats.waited(start, true)
+		return nil
+	}
+	if !common.IsTimeoutErr(err) {
+		return err
+	}
+	err = lwrw.cancelLock(err)
+	lwrw.stats.waited(start, err == nil)
+	return err
+}
+
+// takeGrant resets w.granted flag after a writer has been woken.
+func (lwrw *lwRWMutex) takeGrant() {
+	for {
+		old := (lwRWState)(atomic.LoadInt64(lwrw.state))
+		new := old
+		new.setWriterGranted(false)
+		if atomic.CompareAndSwapInt64(lwrw.state, (int64)(old), (int64)(new)) {
+			return
+		}
+	}
+}
+
+// cancelLock removes a timed out writer from the state.
+// if the lock has already been granted to that writer, it takes the lock and returns nil.
+func (lwrw *lwRWMutex) cancelLock(timeoutErr error) error {
+	for {
+		old := (lwRWState)(atomic.LoadInt64(lwrw.state))
+		if old.writerGranted() && old.writers() == 1 {
+			// we are the only waiting writer, so the lock has been handed over to us.
+			// the waker is about to be signaled, so consume its signal.
+			if err := lwrw.wWaiter.wait(0, -1); err != nil {
+				return err
+			}
+			lwrw.takeGrant()
+			return nil
+		}
+		new := old
+		new.addWriters(-1)
+		var wakeReaders int64
+		if wr := new.waitingReaders(); wr > 0 && !lwrw.readerMustWait(new) {
+			new.addWaitingReaders(-wr)
+			new.addReaders(wr)
+			wakeReaders = wr
+		}
+		if atomic.CompareAndSwapInt64(lwrw.state, (int64)(old), (int64)(new)) {
+			if wakeReaders > 0 {
+				lwrw.rWaiter.wake(int32(wakeReaders))
+			}
+			return timeoutErr
 		}
 	}
 }
 
 func (lwrw *lwRWMutex) rlock() {
-	var new lwRWState
+	if err := lwrw.doRLock(-1); err != nil {
+		panic(err)
+	}
+}
+
+func (lwrw *lwRWMutex) rlockTimeout(timeout time.Duration) bool {
+	err := lwrw.doRLock(timeout)
+	if err == nil {
+		return true
+	}
+	if common.IsTimeoutErr(err) {
+		return false
+	}
+	panic(err)
+}
+
+func (lwrw *lwRWMutex) tryRLock() bool {
 	for {
 		old := (lwRWState)(atomic.LoadInt64(lwrw.state))
-		new = old
-		if new.writers() == 0 {
-			new.addReaders(1)
-		} else {
+		if lwrw.readerMustWait(old) {
+			return false
+		}
+		new := old
+		new.addReaders(1)
+		if atomic.CompareAndSwapInt64(lwrw.state, (int64)(old), (int64)(new)) {
+			lwrw.stats.acquired()
+			return true
+		}
+	}
+}
+
+func (lwrw *lwRWMutex) doRLock(timeout time.Duration) error {
+	var mustWait bool
+	for {
+		old := (lwRWState)(atomic.LoadInt64(lwrw.state))
+		new := old
+		if mustWait = lwrw.readerMustWait(old); mustWait {
 			new.addWaitingReaders(1)
+		} else {
+			new.addReaders(1)
 		}
 		if atomic.CompareAndSwapInt64(lwrw.state, (int64)(old), (int64)(new)) {
 			break
 		}
 	}
-	if new.writers() > 0 {
-		if err := lwrw.rWaiter.wait(0, -1); err != nil {
-			panic(err)
+	if !mustWait {
+		lwrw.stats.acquired()
+		return nil
+	}
+	start := lwrw.stats.start()
+	err := lwrw.rWaiter.wait(0, timeout)
+	if err == nil || !common.IsTimeoutErr(err) {
+		lwrw.stats.waited(start, err == nil)
+		return err
+	}
+	err = lwrw.cancelRLock(err)
+	lwrw.stats.waited(start, err == nil)
+	return err
+}
+
+// cancelRLock removes a timed out reader from the state.
+// if the lock has already been granted to that reader, it takes the lock and returns nil.
+func (lwrw *lwRWMutex) cancelRLock(timeoutErr error) error {
+	for {
+		old := (lwRWState)(atomic.LoadInt64(lwrw.state))
+		if old.waitingReaders() == 0 {
+			// all waiting readers, including us, have become active readers.
+			// the waker is about to be signaled, so consume its signal.
+			return lwrw.rWaiter.wait(0, -1)
+		}
+		new := old
+		new.addWaitingReaders(-1)
+		if atomic.CompareAndSwapInt64(lwrw.state, (int64)(old), (int64)(new)) {
+			return timeoutErr
 		}
 	}
 }
 
 func (lwrw *lwRWMutex) runlock() {
-	new := (lwRWState)(atomic.AddInt64(lwrw.state, -1))
-	if new.readers() == lwRWMMask {
-		panic("unlock of unlocked mutex")
+	lwrw.releaseReader(false)
+}
+
+// releaseReader removes a reader from the state, and, if it was the last one,
+// hands the lock over to a waiting writer.
+// if the only remaining reader is upgrading, the lock is handed over to it.
+// if upgradable is true, the reader must hold the lock in upgradable mode.
+func (lwrw *lwRWMutex) releaseReader(upgradable bool) {
+	var wakeWriter, wakeUpgrader bool
+	for {
+		old := (lwRWState)(atomic.LoadInt64(lwrw.state))
+		if old.readers() == 0 || (upgradable && !old.upgradable()) {
+			panic("unlock of unlocked mutex")
+		}
+		new := old
+		new.addReaders(-1)
+		if upgradable {
+			new.setUpgradable(false)
+		}
+		wakeWriter, wakeUpgrader = false, false
+		if new.upgrading() && new.readers() == 1 {
+			new.addReaders(-1)
+			new.setUpgradable(false)
+			new.addWriters(1)
+			wakeUpgrader = true
+		} else if new.readers() == 0 && new.writers() > 0 {
+			new.setWriterGranted(true)
+			wakeWriter = true
+		}
+		if atomic.CompareAndSwapInt64(lwrw.state, (int64)(old), (int64)(new)) {
+			break
+		}
 	}
-	if new.readers() == 0 && new.writers() > 0 {
+	if wakeWriter {
 		lwrw.wWaiter.wake(1)
+	} else if wakeUpgrader {
+		lwrw.uWaiter.wake(1)
 	}
 }
 
 func (lwrw *lwRWMutex) unlock() {
-	var new lwRWState
+	var wakeReaders int64
+	var wakeWriter bool
 	for {
 		old := (lwRWState)(atomic.LoadInt64(lwrw.state))
-		if old.writers() == 0 {
+		if old.writers() == 0 || old.readers() > 0 || old.writerGranted() {
 			panic("unlock of unlocked mutex")
 		}
-		new = old
+		new := old
 		new.addWriters(-1)
-		if wr := new.waitingReaders(); wr > 0 {
+		wakeReaders, wakeWriter = lwrw.handOver(&new)
+		if atomic.CompareAndSwapInt64(lwrw.state, (int64)(old), (int64)(new)) {
+			break
+		}
+	}
+	if wakeReaders > 0 {
+		lwrw.rWaiter.wake(int32(wakeReaders))
+	} else if wakeWriter {
+		lwrw.wWaiter.wake(1)
+	}
+}
+
+// ulock locks the mutex in upgradable mode.
+// upgradable lock is shared with readers and excludes writers and other upgradable lockers.
+func (lwrw *lwRWMutex) ulock() {
+	lwrw.gate.lock()
+	lwrw.rlock()
+	lwrw.markUpgradable()
+}
+
+func (lwrw *lwRWMutex) tryULock() bool {
+	if !lwrw.gate.tryLock() {
+		return false
+	}
+	if !lwrw.tryRLock() {
+		lwrw.gate.unlock()
+		return false
+	}
+	lwrw.markUpgradable()
+	return true
+}
+
+func (lwrw *lwRWMutex) markUpgradable() {
+	for {
+		old := (lwRWState)(atomic.LoadInt64(lwrw.state))
+		new := old
+		new.setUpgradable(true)
+		if atomic.CompareAndSwapInt64(lwrw.state, (int64)(old), (int64)(new)) {
+			return
+		}
+	}
+}
+
+func (lwrw *lwRWMutex) uunlock() {
+	lwrw.releaseReader(true)
+	lwrw.gate.unlock()
+}
+
+// upgrade converts upgradable lock into exclusive lock.
+// it waits for all other readers to release the lock. new readers are not allowed in meanwhile.
+func (lwrw *lwRWMutex) upgrade() {
+	for {
+		old := (lwRWState)(atomic.LoadInt64(lwrw.state))
+		if !old.upgradable() || old.upgrading() {
+			panic("upgrade of not upgradable-locked mutex")
+		}
+		new := old
+		if old.readers() == 1 {
+			// we are the only reader, become a writer.
+			new.addReaders(-1)
+			new.setUpgradable(false)
+			new.addWriters(1)
+		} else {
+			new.setUpgrading()
+		}
+		if atomic.CompareAndSwapInt64(lwrw.state, (int64)(old), (int64)(new)) {
+			if new.upgrading() {
+				// the last of other readers will make us a writer.
+				if err := lwrw.uWaiter.wait(0, -1); err != nil {
+					panic(err)
+				}
+			}
+			break
+		}
+	}
+	// we are a writer now, so let other upgradable lockers in.
+	lwrw.gate.unlock()
+}
+
+// downgrade atomically converts exclusive lock into a shared one.
+// waiting readers are let in, if the policy allows it.
+func (lwrw *lwRWMutex) downgrade() {
+	var wakeReaders int64
+	for {
+		old := (lwRWState)(atomic.LoadInt64(lwrw.state))
+		if old.writers() == 0 || old.readers() > 0 || old.writerGranted() {
+			panic("downgrade of not locked mutex")
+		}
+		new := old
+		new.addWriters(-1)
+		wakeReaders = 0
+		if wr := new.waitingReaders(); wr > 0 && (new.writers() == 0 || lwrw.getPolicy() != RWMutexWriterPreferring) {
 			new.addWaitingReaders(-wr)
 			new.addReaders(wr)
+			wakeReaders = wr
 		}
+		new.addReaders(1)
 		if atomic.CompareAndSwapInt64(lwrw.state, (int64)(old), (int64)(new)) {
 			break
 		}
 	}
-	if new.readers() > 0 {
-		lwrw.rWaiter.wake(int32(new.readers()))
-	} else if new.writers() > 0 {
-		lwrw.wWaiter.wake(1)
+	if wakeReaders > 0 {
+		lwrw.rWaiter.wake(int32(wakeReaders))
 	}
 }
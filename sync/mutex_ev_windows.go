@@ -9,8 +9,8 @@ import (
 	"github.com/nxgtw/go-ipc/internal/allocator"
 	"github.com/nxgtw/go-ipc/internal/common"
 	"github.com/nxgtw/go-ipc/internal/helper"
-	"bitbucket.org/avd/go-ipc/mmf"
-	"bitbucket.org/avd/go-ipc/shm"
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
 
 	"github.com/pkg/errors"
 	"golang.org/x/sys/windows"
@@ -59,6 +59,7 @@ func NewEventMutex(name string, flag int, perm os.FileMode) (*EventMutex, error)
 		name:   name,
 		lwm:    newLightweightMutex(allocator.ByteSliceData(region.Data()), &eventWaiter{handle: handle}),
 	}
+	result.lwm.dbg = newLockDebug("EventMutex", name)
 	if created {
 		result.lwm.init()
 	}
@@ -75,6 +76,12 @@ func (m *EventMutex) TryLock() bool {
 	return m.lwm.tryLock()
 }
 
+// Locked returns true, if the mutex is held by any process. Unlike TryLock, it doesn't change the state
+// of the mutex, so it can be used to inspect a mutex, which is in use. The result may be outdated at once.
+func (m *EventMutex) Locked() bool {
+	return m.lwm.locked()
+}
+
 // LockTimeout tries to lock the locker, waiting for not more, than timeout.
 func (m *EventMutex) LockTimeout(timeout time.Duration) bool {
 	return m.lwm.lockTimeout(timeout)
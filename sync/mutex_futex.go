@@ -9,13 +9,19 @@ import (
 	"time"
 
 	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/attach"
 	"github.com/nxgtw/go-ipc/internal/helper"
-	"bitbucket.org/avd/go-ipc/mmf"
-	"bitbucket.org/avd/go-ipc/shm"
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
 
 	"github.com/pkg/errors"
 )
 
+const (
+	// FutexMutexSize is the number of bytes occupied by a FutexMutex placed in a memory region.
+	FutexMutexSize = lwmStateSize
+)
+
 // all implementations must satisfy at least IPCLocker interface.
 var (
 	_ TimedIPCLocker = (*FutexMutex)(nil)
@@ -26,35 +32,71 @@ type FutexMutex struct {
 	lwm    *lwMutex
 	region *mmf.MemoryRegion
 	name   string
+	attach *attach.Attachment
 }
 
 // NewFutexMutex creates a new futex-based mutex.
 // This implementation is based on a paper 'Futexes Are Tricky' by Ulrich Drepper,
 // this document can be found in 'docs' folder.
 //	name - object name.
-//	flag - flag is a combination of open flags from 'os' package.
+//	flag - flag is a combination of open flags from 'os' package, O_ATTACH, and O_AUTODESTROY.
 //	perm - object's permission bits.
 func NewFutexMutex(name string, flag int, perm os.FileMode) (*FutexMutex, error) {
-	if err := ensureOpenFlags(flag); err != nil {
+	if err := ensureOpenFlags(attach.StripFlags(flag)); err != nil {
+		return nil, err
+	}
+	att, err := openAttachment(name, "f", flag, perm)
+	if err != nil {
 		return nil, err
 	}
-	region, created, err := helper.CreateWritableRegion(mutexSharedStateName(name, "f"), flag, perm, lwmStateSize)
+	region, created, err := helper.CreateWritableRegion(mutexSharedStateName(name, "f"), attach.StripFlags(flag), perm, lwmStateSize)
 	if err != nil {
+		att.Cancel()
 		return nil, errors.Wrap(err, "failed to create shared state")
 	}
 
+	stats, err := openStats(name, "f", perm)
+	if err != nil {
+		region.Close()
+		if created {
+			shm.DestroyMemoryObject(mutexSharedStateName(name, "f"))
+		}
+		att.Cancel()
+		return nil, err
+	}
 	data := allocator.ByteSliceData(region.Data())
 	result := &FutexMutex{
 		region: region,
 		name:   name,
 		lwm:    newLightweightMutex(data, &futex{ptr: data}),
+		attach: att,
 	}
+	result.lwm.dbg = newLockDebug("FutexMutex", name)
+	result.lwm.stats = stats
 	if created {
 		result.lwm.init()
 	}
 	return result, nil
 }
 
+// NewFutexMutexAt returns a futex-based mutex placed in the region at the given offset.
+// Such a mutex does not own any named objects, so Close and Destroy do nothing,
+// and the region must not be closed while the mutex is in use.
+//	region - memory region, which holds the mutex. it must be writable.
+//	offset - offset of the mutex in the region. it must be 4-byte aligned.
+//	init - if true, the memory is initialized as an unlocked mutex.
+func NewFutexMutexAt(region *mmf.MemoryRegion, offset int, init bool) (*FutexMutex, error) {
+	data, err := regionPointerAt(region, offset, FutexMutexSize, 4)
+	if err != nil {
+		return nil, err
+	}
+	result := &FutexMutex{lwm: newLightweightMutex(data, &futex{ptr: data})}
+	if init {
+		result.lwm.init()
+	}
+	return result, nil
+}
+
 // Lock locks the mutex. It panics on an error.
 func (f *FutexMutex) Lock() {
 	f.lwm.lock()
@@ -65,6 +107,12 @@ func (f *FutexMutex) TryLock() bool {
 	return f.lwm.tryLock()
 }
 
+// Locked returns true, if the mutex is held by any process. Unlike TryLock, it doesn't change the state
+// of the mutex, so it can be used to inspect a mutex, which is in use. The result may be outdated at once.
+func (f *FutexMutex) Locked() bool {
+	return f.lwm.locked()
+}
+
 // LockTimeout tries to lock the locker, waiting for not more, than timeout.
 func (f *FutexMutex) LockTimeout(timeout time.Duration) bool {
 	return f.lwm.lockTimeout(timeout)
@@ -75,14 +123,43 @@ func (f *FutexMutex) Unlock() {
 	f.lwm.unlock()
 }
 
+// Stats returns contention metrics of the mutex.
+// It returns an error, if the mutex was opened with stats disabled. See SetStatsEnabled for details.
+func (f *FutexMutex) Stats() (Stats, error) {
+	return f.lwm.stats.stats()
+}
+
+// Attached returns the number of alive processes, which opened the mutex with O_ATTACH or O_AUTODESTROY.
+// It returns an error, if the mutex was opened without these flags.
+func (f *FutexMutex) Attached() (int, error) {
+	return f.attach.Count()
+}
+
 // Close indicates, that the object is no longer in use,
 // and that the underlying resources can be freed.
+// If the mutex was opened with O_AUTODESTROY, and it was the last attached process, the mutex is destroyed.
 func (f *FutexMutex) Close() error {
-	return f.region.Close()
+	if f.region == nil {
+		return nil
+	}
+	e1, e2 := f.lwm.stats.close(), f.region.Close()
+	e3 := f.attach.Close(func() error {
+		return destroyFutexMutex(f.name)
+	})
+	if e1 != nil {
+		return e1
+	}
+	if e2 != nil {
+		return e2
+	}
+	return e3
 }
 
 // Destroy removes the mutex object.
 func (f *FutexMutex) Destroy() error {
+	if f.region == nil {
+		return nil
+	}
 	if err := f.Close(); err != nil {
 		return errors.Wrap(err, "failed to close shm region")
 	}
@@ -91,8 +168,17 @@ func (f *FutexMutex) Destroy() error {
 
 // DestroyFutexMutex permanently removes mutex with the given name.
 func DestroyFutexMutex(name string) error {
+	if err := destroyFutexMutex(name); err != nil {
+		return err
+	}
+	return destroyAttachment(name, "f")
+}
+
+// destroyFutexMutex removes the objects of the mutex except its attach table,
+// which is removed by the last attached process itself.
+func destroyFutexMutex(name string) error {
 	if err := shm.DestroyMemoryObject(mutexSharedStateName(name, "f")); err != nil {
 		return errors.Wrap(err, "failed to destroy memory object")
 	}
-	return nil
+	return destroyStats(name, "f")
 }
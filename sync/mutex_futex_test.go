@@ -6,7 +6,14 @@ package sync
 
 import (
 	"os"
+	"sync"
 	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -29,3 +36,88 @@ func BenchmarkFutexMutexAsRW(b *testing.B) {
 	defer m.Close()
 	benchmarkRWLocker(b, m, m)
 }
+
+func TestFutexMutexAt(t *testing.T) {
+	const (
+		routines = 8
+		iters    = 10000
+	)
+	a := assert.New(t)
+	if !a.NoError(shm.DestroyMemoryObject(testMemObj)) {
+		return
+	}
+	defer shm.DestroyMemoryObject(testMemObj)
+	region, err := createMemoryRegionSimple(os.O_CREATE|os.O_EXCL|os.O_RDWR, mmf.MEM_READWRITE, 64, 0)
+	if !a.NoError(err) {
+		return
+	}
+	defer region.Close()
+	_, err = NewFutexMutexAt(region, 62, true)
+	a.Error(err)
+	_, err = NewFutexMutexAt(region, 1, true)
+	a.Error(err)
+	// the record consists of a mutex and a counter, protected by it.
+	m, err := NewFutexMutexAt(region, 8, true)
+	if !a.NoError(err) {
+		return
+	}
+	m2, err := NewFutexMutexAt(region, 8, false)
+	if !a.NoError(err) {
+		return
+	}
+	value := (*int64)(unsafe.Pointer(uintptr(allocator.ByteSliceData(region.Data())) + 8 + FutexMutexSize + 4))
+	var wg sync.WaitGroup
+	wg.Add(routines)
+	for i := 0; i < routines; i++ {
+		lk := m
+		if i%2 == 0 {
+			lk = m2
+		}
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iters; i++ {
+				lk.Lock()
+				*value++
+				lk.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	a.Equal(int64(routines*iters), *value)
+	a.NoError(m.Destroy())
+	a.NoError(m2.Close())
+}
+
+func TestFutexMutexStats(t *testing.T) {
+	a := assert.New(t)
+	SetStatsEnabled(true)
+	defer SetStatsEnabled(false)
+	if !a.NoError(DestroyFutexMutex(testStatsObjName)) {
+		return
+	}
+	m, err := NewFutexMutex(testStatsObjName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m.Destroy()
+	m2, err := NewFutexMutex(testStatsObjName, 0, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m2.Close()
+	m.Lock()
+	a.False(m2.TryLock())
+	a.False(m2.LockTimeout(time.Millisecond * 10))
+	m.Unlock()
+	m2.Lock()
+	m2.Unlock()
+	st, err := m2.Stats()
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(uint64(1), st.Process.Acquisitions)
+	a.Equal(uint64(1), st.Process.Contended)
+	a.Equal(uint64(1), st.Process.Timeouts)
+	a.Equal(uint64(2), st.Shared.Acquisitions)
+	a.True(st.Shared.WaitTime >= time.Millisecond*10)
+}
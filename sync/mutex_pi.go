@@ -0,0 +1,166 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build linux
+
+package sync
+
+import (
+	"os"
+	"runtime"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/common"
+	"github.com/nxgtw/go-ipc/internal/helper"
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	piMutexStateSize = 4
+)
+
+// all implementations must satisfy at least IPCLocker interface.
+var (
+	_ TimedIPCLocker = (*PIMutex)(nil)
+)
+
+// PIMutex is a priority-inheritance mutex based on linux PI-futexes.
+// If a thread waits for the mutex, held by a lower priority thread, the kernel boosts the priority
+// of the owner, so that it could release the mutex as soon as possible. It also works across processes.
+// The shared state of the mutex contains the TID of the owner thread, so the goroutine,
+// that locked the mutex, is wired to its current thread until it unlocks the mutex.
+// The mutex must be unlocked by the same goroutine, that has locked it. It is not recursive.
+type PIMutex struct {
+	state  unsafe.Pointer
+	region *mmf.MemoryRegion
+	name   string
+}
+
+// NewPIMutex creates a new priority-inheritance mutex.
+//	name - object name.
+//	flag - flag is a combination of open flags from 'os' package.
+//	perm - object's permission bits.
+func NewPIMutex(name string, flag int, perm os.FileMode) (*PIMutex, error) {
+	if err := ensureOpenFlags(flag); err != nil {
+		return nil, err
+	}
+	region, created, err := helper.CreateWritableRegion(mutexSharedStateName(name, "pi"), flag, perm, piMutexStateSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create shared state")
+	}
+	result := &PIMutex{
+		state:  allocator.ByteSliceData(region.Data()),
+		region: region,
+		name:   name,
+	}
+	if created {
+		*result.addr() = 0
+	}
+	return result, nil
+}
+
+func (pi *PIMutex) addr() *int32 {
+	return (*int32)(pi.state)
+}
+
+// Lock locks the mutex. It panics on an error.
+func (pi *PIMutex) Lock() {
+	if err := pi.doLock(-1); err != nil {
+		panic(err)
+	}
+}
+
+// TryLock makes one attempt to lock the mutex. It return true on succeess and false otherwise.
+func (pi *PIMutex) TryLock() bool {
+	runtime.LockOSThread()
+	if atomic.CompareAndSwapInt32(pi.addr(), 0, int32(unix.Gettid())) {
+		return true
+	}
+	// the mutex is either locked, or its state has been changed by the kernel.
+	err := FutexTryLockPI(pi.state, 0)
+	if err == nil {
+		return true
+	}
+	runtime.UnlockOSThread()
+	if common.SyscallErrHasCode(err, unix.EWOULDBLOCK) || common.SyscallErrHasCode(err, unix.EDEADLK) {
+		return false
+	}
+	panic(err)
+}
+
+// LockTimeout tries to lock the mutex, waiting for not more, than timeout.
+// It returns false, if the mutex is already held by the calling goroutine.
+func (pi *PIMutex) LockTimeout(timeout time.Duration) bool {
+	err := pi.doLock(timeout)
+	if err == nil {
+		return true
+	}
+	if common.IsTimeoutErr(err) || common.SyscallErrHasCode(err, unix.EDEADLK) {
+		return false
+	}
+	panic(err)
+}
+
+func (pi *PIMutex) doLock(timeout time.Duration) error {
+	runtime.LockOSThread()
+	if atomic.CompareAndSwapInt32(pi.addr(), 0, int32(unix.Gettid())) {
+		return nil
+	}
+	err := FutexLockPI(pi.state, timeout, 0)
+	if err != nil {
+		runtime.UnlockOSThread()
+	}
+	return err
+}
+
+// Unlock releases the mutex. It panics on an error, if the mutex is not locked,
+// or if it is held by another thread.
+func (pi *PIMutex) Unlock() {
+	tid := int32(unix.Gettid())
+	old := atomic.LoadInt32(pi.addr())
+	if owner := old & cFUTEX_TID_MASK; owner == 0 {
+		panic("unlock of unlocked mutex")
+	} else if owner != tid {
+		panic("unlock of mutex held by another thread")
+	}
+	if !atomic.CompareAndSwapInt32(pi.addr(), tid, 0) {
+		// there are waiters, the kernel will pass the mutex to one of them.
+		if err := FutexUnlockPI(pi.state, 0); err != nil {
+			panic(err)
+		}
+	}
+	runtime.UnlockOSThread()
+}
+
+// Owner returns the TID of the thread, which holds the mutex, or 0, if the mutex is not locked.
+func (pi *PIMutex) Owner() int {
+	return int(atomic.LoadInt32(pi.addr()) & cFUTEX_TID_MASK)
+}
+
+// Close indicates, that the object is no longer in use,
+// and that the underlying resources can be freed.
+func (pi *PIMutex) Close() error {
+	return pi.region.Close()
+}
+
+// Destroy removes the mutex object.
+func (pi *PIMutex) Destroy() error {
+	if err := pi.Close(); err != nil {
+		return errors.Wrap(err, "failed to close shm region")
+	}
+	return DestroyPIMutex(pi.name)
+}
+
+// DestroyPIMutex permanently removes mutex with the given name.
+func DestroyPIMutex(name string) error {
+	if err := shm.DestroyMemoryObject(mutexSharedStateName(name, "pi")); err != nil {
+		return errors.Wrap(err, "failed to destroy memory object")
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build linux
+
+package sync
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func piMutexCtor(name string, flag int, perm os.FileMode) (IPCLocker, error) {
+	return NewPIMutex(name, flag, perm)
+}
+
+func piMutexDtor(name string) error {
+	return DestroyPIMutex(name)
+}
+
+func TestPIMutexOpenMode(t *testing.T) {
+	testLockerOpenMode(t, piMutexCtor, piMutexDtor)
+}
+
+func TestPIMutexOpenMode2(t *testing.T) {
+	testLockerOpenMode2(t, piMutexCtor, piMutexDtor)
+}
+
+func TestPIMutexOpenMode3(t *testing.T) {
+	testLockerOpenMode3(t, piMutexCtor, piMutexDtor)
+}
+
+func TestPIMutexOpenMode4(t *testing.T) {
+	testLockerOpenMode4(t, piMutexCtor, piMutexDtor)
+}
+
+func TestPIMutexOpenMode5(t *testing.T) {
+	testLockerOpenMode5(t, piMutexCtor, piMutexDtor)
+}
+
+func TestPIMutexLock(t *testing.T) {
+	testLockerLock(t, piMutexCtor, piMutexDtor)
+}
+
+func TestPIMutexMemory(t *testing.T) {
+	testLockerMemory(t, "pi", false, piMutexCtor, piMutexDtor)
+}
+
+func TestPIMutexValueInc(t *testing.T) {
+	testLockerValueInc(t, "pi", piMutexCtor, piMutexDtor)
+}
+
+func TestPIMutexLockTimeout(t *testing.T) {
+	testLockerLockTimeout(t, "pi", piMutexCtor, piMutexDtor)
+}
+
+func TestPIMutexLockTimeout2(t *testing.T) {
+	testLockerLockTimeout2(t, "pi", piMutexCtor, piMutexDtor)
+}
+
+func TestPIMutexPanicsOnDoubleUnlock(t *testing.T) {
+	testLockerTwiceUnlock(t, piMutexCtor, piMutexDtor)
+}
+
+func TestPIMutexOwner(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyPIMutex(testLockerName)) {
+		return
+	}
+	m, err := NewPIMutex(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(m.Destroy())
+	}()
+	a.Equal(0, m.Owner())
+	m.Lock()
+	a.Equal(unix.Gettid(), m.Owner())
+	// the mutex is not recursive.
+	a.False(m.TryLock())
+	ch := make(chan bool)
+	go func() {
+		ok := m.TryLock()
+		if ok {
+			m.Unlock()
+		}
+		ch <- ok
+		a.PanicsWithValue("unlock of mutex held by another thread", func() {
+			m.Unlock()
+		})
+		ch <- m.LockTimeout(time.Millisecond * 50)
+	}()
+	a.False(<-ch)
+	a.False(<-ch)
+	m.Unlock()
+	a.Equal(0, m.Owner())
+	if a.True(m.TryLock()) {
+		m.Unlock()
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"os"
+	"runtime"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/helper"
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// recursive mutex state layout:
+	//	lwMutex state (int32) | owner pid (int32) | owner tid (int64) | depth (int32) | padding
+	recursiveMutexStateSize = 24
+)
+
+// all implementations must satisfy at least IPCLocker interface.
+var (
+	_ TimedIPCLocker = (*RecursiveMutex)(nil)
+)
+
+// RecursiveMutex is a mutex, which can be locked several times by its owner.
+// The owner must unlock the mutex as many times, as it has locked it.
+// The owner is identified by its process and thread ids, so the goroutine,
+// that locked the mutex, is wired to its current thread until it fully unlocks the mutex.
+// The mutex must be unlocked by the same goroutine, that has locked it.
+type RecursiveMutex struct {
+	lwm    *lwMutex
+	ww     waitWaker
+	region *mmf.MemoryRegion
+	pid    *int32
+	tid    *int64
+	depth  *int32
+	name   string
+}
+
+// NewRecursiveMutex creates a new recursive mutex.
+//	name - object name.
+//	flag - flag is a combination of open flags from 'os' package.
+//	perm - object's permission bits.
+func NewRecursiveMutex(name string, flag int, perm os.FileMode) (*RecursiveMutex, error) {
+	if err := ensureOpenFlags(flag); err != nil {
+		return nil, err
+	}
+	region, created, err := helper.CreateWritableRegion(mutexSharedStateName(name, "rec"), flag, perm, recursiveMutexStateSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create shared state")
+	}
+	data := allocator.ByteSliceData(region.Data())
+	ww, err := makeRecursiveMutexWaiter(name, flag, perm, data)
+	if err != nil {
+		region.Close()
+		if created {
+			shm.DestroyMemoryObject(mutexSharedStateName(name, "rec"))
+		}
+		return nil, err
+	}
+	result := &RecursiveMutex{
+		lwm:    newLightweightMutex(data, ww),
+		ww:     ww,
+		region: region,
+		pid:    (*int32)(unsafe.Pointer(uintptr(data) + 4)),
+		tid:    (*int64)(unsafe.Pointer(uintptr(data) + 8)),
+		depth:  (*int32)(unsafe.Pointer(uintptr(data) + 16)),
+		name:   name,
+	}
+	result.lwm.dbg = newLockDebug("RecursiveMutex", name)
+	if created {
+		result.lwm.init()
+		*result.pid = 0
+		*result.tid = 0
+		*result.depth = 0
+	}
+	return result, nil
+}
+
+// Lock locks the mutex. If the mutex is already held by the caller, it increments recursion depth.
+// It panics on an error.
+func (rm *RecursiveMutex) Lock() {
+	if rm.relock() {
+		return
+	}
+	runtime.LockOSThread()
+	rm.lwm.lock()
+	rm.setOwner()
+}
+
+// TryLock makes one attempt to lock the mutex. It return true on succeess and false otherwise.
+func (rm *RecursiveMutex) TryLock() bool {
+	if rm.relock() {
+		return true
+	}
+	runtime.LockOSThread()
+	if !rm.lwm.tryLock() {
+		runtime.UnlockOSThread()
+		return false
+	}
+	rm.setOwner()
+	return true
+}
+
+// LockTimeout tries to lock the mutex, waiting for not more, than timeout.
+func (rm *RecursiveMutex) LockTimeout(timeout time.Duration) bool {
+	if rm.relock() {
+		return true
+	}
+	runtime.LockOSThread()
+	if !rm.lwm.lockTimeout(timeout) {
+		runtime.UnlockOSThread()
+		return false
+	}
+	rm.setOwner()
+	return true
+}
+
+// Unlock decrements recursion depth of the mutex and releases it, if the depth becomes zero.
+// It panics, if the mutex is not locked, or if it is held by another goroutine or process.
+func (rm *RecursiveMutex) Unlock() {
+	if pid, tid := rm.Owner(); pid == 0 {
+		panic("unlock of unlocked mutex")
+	} else if !rm.ownedByCaller() {
+		panic(errors.Errorf("unlock of a recursive mutex held by another owner (pid=%d, tid=%d)", pid, tid))
+	}
+	if *rm.depth--; *rm.depth > 0 {
+		return
+	}
+	atomic.StoreInt64(rm.tid, 0)
+	atomic.StoreInt32(rm.pid, 0)
+	rm.lwm.unlock()
+	runtime.UnlockOSThread()
+}
+
+// Owner returns process and thread ids of the mutex owner.
+// If the mutex is not locked, both ids are zero.
+func (rm *RecursiveMutex) Owner() (pid int, tid int64) {
+	return int(atomic.LoadInt32(rm.pid)), atomic.LoadInt64(rm.tid)
+}
+
+// Close indicates, that the object is no longer in use,
+// and that the underlying resources can be freed.
+func (rm *RecursiveMutex) Close() error {
+	e1, e2 := closeRecursiveMutexWaiter(rm.ww), rm.region.Close()
+	if e1 != nil {
+		return e1
+	}
+	if e2 != nil {
+		return errors.Wrap(e2, "failed to close shm region")
+	}
+	return nil
+}
+
+// Destroy removes the mutex object.
+func (rm *RecursiveMutex) Destroy() error {
+	if err := rm.Close(); err != nil {
+		return errors.Wrap(err, "failed to close recursive mutex")
+	}
+	return DestroyRecursiveMutex(rm.name)
+}
+
+// DestroyRecursiveMutex permanently removes mutex with the given name.
+func DestroyRecursiveMutex(name string) error {
+	if err := shm.DestroyMemoryObject(mutexSharedStateName(name, "rec")); err != nil {
+		return errors.Wrap(err, "failed to destroy memory object")
+	}
+	return destroyRecursiveMutexWaiter(name)
+}
+
+// ownedByCaller returns true, if the mutex is held by the calling goroutine.
+// as the owner goroutine is locked on its thread, no other goroutine can have the same thread id.
+func (rm *RecursiveMutex) ownedByCaller() bool {
+	return int(atomic.LoadInt32(rm.pid)) == os.Getpid() && atomic.LoadInt64(rm.tid) == currentThreadID()
+}
+
+// relock increments recursion depth, if the mutex is held by the caller.
+func (rm *RecursiveMutex) relock() bool {
+	if !rm.ownedByCaller() {
+		return false
+	}
+	*rm.depth++
+	return true
+}
+
+func (rm *RecursiveMutex) setOwner() {
+	*rm.depth = 1
+	atomic.StoreInt64(rm.tid, currentThreadID())
+	atomic.StoreInt32(rm.pid, int32(os.Getpid()))
+}
@@ -0,0 +1,23 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build linux,!sysv_sema_linux
+
+package sync
+
+import (
+	"os"
+	"unsafe"
+)
+
+// makeRecursiveMutexWaiter uses lwMutex state as a futex.
+func makeRecursiveMutexWaiter(name string, flag int, perm os.FileMode, state unsafe.Pointer) (waitWaker, error) {
+	return &futex{ptr: state}, nil
+}
+
+func closeRecursiveMutexWaiter(ww waitWaker) error {
+	return nil
+}
+
+func destroyRecursiveMutexWaiter(name string) error {
+	return nil
+}
@@ -0,0 +1,38 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build !linux sysv_sema_linux
+
+package sync
+
+import (
+	"os"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+func makeRecursiveMutexWaiter(name string, flag int, perm os.FileMode, unused unsafe.Pointer) (waitWaker, error) {
+	s, err := openSemaphore(recursiveMutexSemaName(name), flag, perm, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create a semaphore")
+	}
+	return newSemaWaiter(s), nil
+}
+
+func closeRecursiveMutexWaiter(ww waitWaker) error {
+	if err := ww.(*semaWaiter).s.Close(); err != nil {
+		return errors.Wrap(err, "failed to close a semaphore")
+	}
+	return nil
+}
+
+func destroyRecursiveMutexWaiter(name string) error {
+	if err := DestroySemaphore(recursiveMutexSemaName(name)); err != nil {
+		return errors.Wrap(err, "failed to destroy a semaphore")
+	}
+	return nil
+}
+
+func recursiveMutexSemaName(name string) string {
+	return name + ".recs"
+}
@@ -0,0 +1,108 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func recMutexCtor(name string, flag int, perm os.FileMode) (IPCLocker, error) {
+	return NewRecursiveMutex(name, flag, perm)
+}
+
+func recMutexDtor(name string) error {
+	return DestroyRecursiveMutex(name)
+}
+
+func TestRecursiveMutexOpenMode(t *testing.T) {
+	testLockerOpenMode(t, recMutexCtor, recMutexDtor)
+}
+
+func TestRecursiveMutexOpenMode2(t *testing.T) {
+	testLockerOpenMode2(t, recMutexCtor, recMutexDtor)
+}
+
+func TestRecursiveMutexOpenMode3(t *testing.T) {
+	testLockerOpenMode3(t, recMutexCtor, recMutexDtor)
+}
+
+func TestRecursiveMutexOpenMode4(t *testing.T) {
+	testLockerOpenMode4(t, recMutexCtor, recMutexDtor)
+}
+
+func TestRecursiveMutexOpenMode5(t *testing.T) {
+	testLockerOpenMode5(t, recMutexCtor, recMutexDtor)
+}
+
+func TestRecursiveMutexLock(t *testing.T) {
+	testLockerLock(t, recMutexCtor, recMutexDtor)
+}
+
+func TestRecursiveMutexMemory(t *testing.T) {
+	testLockerMemory(t, "rec", false, recMutexCtor, recMutexDtor)
+}
+
+func TestRecursiveMutexValueInc(t *testing.T) {
+	testLockerValueInc(t, "rec", recMutexCtor, recMutexDtor)
+}
+
+func TestRecursiveMutexLockTimeout2(t *testing.T) {
+	testLockerLockTimeout2(t, "rec", recMutexCtor, recMutexDtor)
+}
+
+func TestRecursiveMutexPanicsOnDoubleUnlock(t *testing.T) {
+	testLockerTwiceUnlock(t, recMutexCtor, recMutexDtor)
+}
+
+func TestRecursiveMutexRecursion(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyRecursiveMutex(testLockerName)) {
+		return
+	}
+	m, err := NewRecursiveMutex(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(m.Destroy())
+	}()
+	m.Lock()
+	a.True(m.TryLock())
+	a.True(m.LockTimeout(time.Millisecond))
+	pid, tid := m.Owner()
+	a.Equal(os.Getpid(), pid)
+	a.Equal(currentThreadID(), tid)
+	ch := make(chan bool)
+	go func() {
+		ch <- m.TryLock()
+		// the mutex is held by another goroutine.
+		a.Panics(func() {
+			m.Unlock()
+		})
+		ch <- m.LockTimeout(time.Millisecond * 50)
+	}()
+	a.False(<-ch)
+	a.False(<-ch)
+	m.Unlock()
+	m.Unlock()
+	pid, _ = m.Owner()
+	a.Equal(os.Getpid(), pid)
+	m.Unlock()
+	pid, tid = m.Owner()
+	a.Equal(0, pid)
+	a.Equal(int64(0), tid)
+	go func() {
+		ch <- m.TryLock()
+		m.Unlock()
+		ch <- true
+	}()
+	a.True(<-ch)
+	<-ch
+	a.Panics(func() {
+		m.Unlock()
+	})
+}
@@ -8,8 +8,8 @@ import (
 
 	"github.com/nxgtw/go-ipc/internal/allocator"
 	"github.com/nxgtw/go-ipc/internal/helper"
-	"bitbucket.org/avd/go-ipc/mmf"
-	"bitbucket.org/avd/go-ipc/shm"
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
 
 	"github.com/pkg/errors"
 )
@@ -39,7 +39,7 @@ func NewSemaMutex(name string, flag int, perm os.FileMode) (*SemaMutex, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create shared state")
 	}
-	s, err := NewSemaphore(name, flag, perm, 1)
+	s, err := openSemaphore(name, flag, perm, 1)
 	if err != nil {
 		region.Close()
 		if created {
@@ -53,6 +53,7 @@ func NewSemaMutex(name string, flag int, perm os.FileMode) (*SemaMutex, error) {
 		name:   name,
 		lwm:    newLightweightMutex(allocator.ByteSliceData(region.Data()), newSemaWaiter(s)),
 	}
+	result.lwm.dbg = newLockDebug("SemaMutex", name)
 	if created {
 		result.lwm.init()
 	}
@@ -74,6 +75,12 @@ func (m *SemaMutex) TryLock() bool {
 	return m.lwm.tryLock()
 }
 
+// Locked returns true, if the mutex is held by any process. Unlike TryLock, it doesn't change the state
+// of the mutex, so it can be used to inspect a mutex, which is in use. The result may be outdated at once.
+func (m *SemaMutex) Locked() bool {
+	return m.lwm.locked()
+}
+
 // Unlock releases the mutex. It panics on an error, or if the mutex is not locked.
 func (m *SemaMutex) Unlock() {
 	m.lwm.unlock()
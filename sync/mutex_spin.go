@@ -5,12 +5,14 @@ package sync
 import (
 	"os"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/attach"
 	"github.com/nxgtw/go-ipc/internal/helper"
-	"bitbucket.org/avd/go-ipc/mmf"
-	"bitbucket.org/avd/go-ipc/shm"
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
 
 	"github.com/pkg/errors"
 )
@@ -25,6 +27,7 @@ type SpinMutex struct {
 	lwm    *lwMutex
 	region *mmf.MemoryRegion
 	name   string
+	attach *attach.Attachment
 }
 
 type spinWW struct{}
@@ -40,22 +43,29 @@ func (sw spinWW) wait(unused int32, timeout time.Duration) error {
 
 // NewSpinMutex creates a new spin mutex.
 //	name - object name.
-//	flag - flag is a combination of open flags from 'os' package.
+//	flag - flag is a combination of open flags from 'os' package, O_ATTACH, and O_AUTODESTROY.
 //	perm - object's permission bits.
 func NewSpinMutex(name string, flag int, perm os.FileMode) (*SpinMutex, error) {
-	if err := ensureOpenFlags(flag); err != nil {
+	if err := ensureOpenFlags(attach.StripFlags(flag)); err != nil {
+		return nil, err
+	}
+	att, err := openAttachment(name, "spin", flag, perm)
+	if err != nil {
 		return nil, err
 	}
 	name = spinName(name)
-	region, created, err := helper.CreateWritableRegion(name, flag, perm, lwmStateSize)
+	region, created, err := helper.CreateWritableRegion(name, attach.StripFlags(flag), perm, lwmStateSize)
 	if err != nil {
+		att.Cancel()
 		return nil, err
 	}
 	result := &SpinMutex{
 		region: region,
 		name:   name,
 		lwm:    newLightweightMutex(allocator.ByteSliceData(region.Data()), new(spinWW)),
+		attach: att,
 	}
+	result.lwm.dbg = newLockDebug("SpinMutex", name)
 	if created {
 		result.lwm.init()
 	}
@@ -82,10 +92,30 @@ func (spin *SpinMutex) TryLock() bool {
 	return spin.lwm.tryLock()
 }
 
+// Locked returns true, if the mutex is held by any process. Unlike TryLock, it doesn't change the state
+// of the mutex, so it can be used to inspect a mutex, which is in use. The result may be outdated at once.
+func (spin *SpinMutex) Locked() bool {
+	return spin.lwm.locked()
+}
+
+// Attached returns the number of alive processes, which opened the mutex with O_ATTACH or O_AUTODESTROY.
+// It returns an error, if the mutex was opened without these flags.
+func (spin *SpinMutex) Attached() (int, error) {
+	return spin.attach.Count()
+}
+
 // Close indicates, that the object is no longer in use,
 // and that the underlying resources can be freed.
+// If the mutex was opened with O_AUTODESTROY, and it was the last attached process, the mutex is destroyed.
 func (spin *SpinMutex) Close() error {
-	return spin.region.Close()
+	err := spin.region.Close()
+	name := spin.name
+	if errAttach := spin.attach.Close(func() error {
+		return shm.DestroyMemoryObject(name)
+	}); err == nil {
+		err = errAttach
+	}
+	return err
 }
 
 // Destroy removes the mutex object.
@@ -95,18 +125,24 @@ func (spin *SpinMutex) Destroy() error {
 	}
 	spin.region = nil
 	err := shm.DestroyMemoryObject(spin.name)
+	errAttach := destroyAttachment(strings.TrimPrefix(spin.name, spinPrefix), "spin")
 	spin.name = ""
 	if err != nil {
 		return errors.Wrap(err, "failed to destroy shm object")
 	}
-	return nil
+	return errAttach
 }
 
 // DestroySpinMutex removes a mutex object with the given name
 func DestroySpinMutex(name string) error {
-	return shm.DestroyMemoryObject(spinName(name))
+	if err := shm.DestroyMemoryObject(spinName(name)); err != nil {
+		return err
+	}
+	return destroyAttachment(name, "spin")
 }
 
+const spinPrefix = "go-ipc.spin."
+
 func spinName(name string) string {
-	return "go-ipc.spin." + name
+	return spinPrefix + name
 }
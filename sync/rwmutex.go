@@ -4,90 +4,277 @@ package sync
 
 import (
 	"os"
+	"time"
 
 	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/attach"
 	"github.com/nxgtw/go-ipc/internal/helper"
-	"bitbucket.org/avd/go-ipc/mmf"
-	"bitbucket.org/avd/go-ipc/shm"
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
 
 	"github.com/pkg/errors"
 )
 
 // all implementations must satisfy at least IPCLocker interface.
 var (
-	_ IPCLocker = (*RWMutex)(nil)
+	_ TimedIPCLocker = (*RWMutex)(nil)
+)
+
+// RWMutexPolicy defines, how an RWMutex is handed over between readers and writers.
+type RWMutexPolicy int32
+
+const (
+	// RWMutexPhaseFair is the default policy. New readers wait, if there are waiting writers.
+	// When a writer releases the lock, all waiting readers acquire it, if there are any.
+	// Otherwise, the lock is given to one of the writers. This policy prevents both readers and writers starvation.
+	RWMutexPhaseFair RWMutexPolicy = iota
+	// RWMutexReaderPreferring policy lets new readers acquire the lock, if it is held by other readers,
+	// even if there are waiting writers. Writers may starve with this policy.
+	RWMutexReaderPreferring
+	// RWMutexWriterPreferring policy makes new readers wait, if there are waiting writers.
+	// When a writer releases the lock, it is given to the next writer, if any. Readers may starve with this policy.
+	RWMutexWriterPreferring
 )
 
 // RWMutex is a mutex, that can be held by any number of readers or one writer.
+// On linux, unless built with 'sysv_sema_linux' tag, its waiters are futexes kept in the shared state,
+// so it can also be placed in a memory region with NewRWMutexAt and NewRWMutexPolicyAt.
+// On other platforms, and with 'sysv_sema_linux' tag, the waiters are SysV semaphores,
+// which can't be placed in a memory region, so these functions and RWMutexSize are not available.
 type RWMutex struct {
-	lwm    *lwRWMutex
-	region *mmf.MemoryRegion
-	wR, wW waitWaker
-	name   string
+	lwm     *lwRWMutex
+	region  *mmf.MemoryRegion
+	waiters rwmWaiters
+	name    string
+	dbg     lockDebug
+	attach  *attach.Attachment
 }
 
-// NewRWMutex returns new RWMutex
+// NewRWMutex returns new RWMutex with the phase-fair policy.
 //	name - object name.
-//	flag - flag is a combination of open flags from 'os' package.
+//	flag - flag is a combination of open flags from 'os' package, O_ATTACH, and O_AUTODESTROY.
 //	perm - object's permission bits.
 func NewRWMutex(name string, flag int, perm os.FileMode) (*RWMutex, error) {
-	if err := ensureOpenFlags(flag); err != nil {
+	return NewRWMutexPolicy(name, flag, perm, RWMutexPhaseFair)
+}
+
+// NewRWMutexPolicy returns new RWMutex with the given policy.
+// The policy is stored in the shared state, so that all processes use the same policy.
+// If an existing mutex is opened, its policy is used and the policy argument is ignored.
+//	name - object name.
+//	flag - flag is a combination of open flags from 'os' package, O_ATTACH, and O_AUTODESTROY.
+//	perm - object's permission bits.
+//	policy - the policy for a newly created mutex.
+func NewRWMutexPolicy(name string, flag int, perm os.FileMode, policy RWMutexPolicy) (*RWMutex, error) {
+	if err := ensureRWMutexPolicy(policy); err != nil {
+		return nil, err
+	}
+	if err := ensureOpenFlags(attach.StripFlags(flag)); err != nil {
 		return nil, err
 	}
-	region, created, err := helper.CreateWritableRegion(mutexSharedStateName(name, "rw"), flag, perm, lwRWMStateSize)
+	att, err := openAttachment(name, "rw", flag, perm)
 	if err != nil {
+		return nil, err
+	}
+	flag = attach.StripFlags(flag)
+	region, created, err := helper.CreateWritableRegion(mutexSharedStateName(name, "rw"), flag, perm, lwRWMStateSize+rwmWaitersStateSize)
+	if err != nil {
+		att.Cancel()
 		return nil, errors.Wrap(err, "failed to create shared state")
 	}
-	result := &RWMutex{region: region, name: name}
-	if result.wR, result.wW, err = makeRWMWaiters(name, flag, perm); err != nil {
+	result := &RWMutex{region: region, name: name, dbg: newLockDebug("RWMutex", name), attach: att}
+	data := allocator.ByteSliceData(region.Data())
+	if result.waiters, err = makeRWMWaiters(name, flag, perm, data, created); err != nil {
 		region.Close()
 		if created {
 			shm.DestroyMemoryObject(mutexSharedStateName(name, "rw"))
 		}
+		att.Cancel()
+		return nil, err
+	}
+	result.lwm = newRWLightweightMutex(data, result.waiters)
+	if result.lwm.stats, err = openStats(name, "rw", perm); err != nil {
+		closeRWWaiters(result.waiters)
+		region.Close()
+		if created {
+			DestroyRWMutex(name)
+		}
+		att.Cancel()
 		return nil, err
 	}
-	result.lwm = newRWLightweightMutex(allocator.ByteSliceData(region.Data()), result.wR, result.wW)
 	if created {
-		result.lwm.init()
+		result.lwm.init(policy)
 	}
 	return result, nil
 }
 
+func ensureRWMutexPolicy(policy RWMutexPolicy) error {
+	if policy < RWMutexPhaseFair || policy > RWMutexWriterPreferring {
+		return errors.Errorf("invalid rwmutex policy %d", policy)
+	}
+	return nil
+}
+
+// Policy returns the policy of the mutex.
+func (rw *RWMutex) Policy() RWMutexPolicy {
+	return rw.lwm.getPolicy()
+}
+
 // Lock locks the mutex exclusively. It panics on an error.
 func (rw *RWMutex) Lock() {
+	rw.dbg.wait()
 	rw.lwm.lock()
+	rw.dbg.acquired()
+}
+
+// LockTimeout tries to lock the mutex exclusively, waiting for not more, than timeout.
+func (rw *RWMutex) LockTimeout(timeout time.Duration) bool {
+	rw.dbg.wait()
+	return rw.debugResult(rw.lwm.lockTimeout(timeout))
+}
+
+// TryLock makes one attempt to lock the mutex exclusively. It returns true on succeess and false otherwise.
+func (rw *RWMutex) TryLock() bool {
+	if rw.lwm.tryLock() {
+		rw.dbg.acquired()
+		return true
+	}
+	return false
+}
+
+// State returns the number of readers, which hold the mutex, and the number of writers,
+// which hold the mutex or wait for it. It doesn't change the state of the mutex,
+// so it can be used to inspect a mutex, which is in use. The result may be outdated at once.
+func (rw *RWMutex) State() (readers, writers int) {
+	s := rw.lwm.load()
+	return int(s.readers()), int(s.writers())
 }
 
 // Unlock releases the mutex. It panics on an error, or if the mutex is not locked.
 func (rw *RWMutex) Unlock() {
+	rw.dbg.released()
 	rw.lwm.unlock()
 }
 
 // RLock locks the mutex for reading. It panics on an error.
 func (rw *RWMutex) RLock() {
+	rw.dbg.wait()
 	rw.lwm.rlock()
+	rw.dbg.acquired()
+}
+
+// RLockTimeout tries to lock the mutex for reading, waiting for not more, than timeout.
+func (rw *RWMutex) RLockTimeout(timeout time.Duration) bool {
+	rw.dbg.wait()
+	return rw.debugResult(rw.lwm.rlockTimeout(timeout))
+}
+
+// TryRLock makes one attempt to lock the mutex for reading. It returns true on succeess and false otherwise.
+func (rw *RWMutex) TryRLock() bool {
+	if rw.lwm.tryRLock() {
+		rw.dbg.acquired()
+		return true
+	}
+	return false
 }
 
 // RUnlock desceases the number of mutex's readers. If it becomes 0, writers (if any) can proceed.
 // It panics on an error, or if the mutex is not locked.
 func (rw *RWMutex) RUnlock() {
+	rw.dbg.released()
 	rw.lwm.runlock()
 }
 
+// ULock locks the mutex in upgradable mode. It panics on an error.
+// Upgradable lock can be held together with read locks, but excludes writers
+// and other upgradable lockers. Later it can be atomically converted to
+// an exclusive lock with UpgradeToLock.
+func (rw *RWMutex) ULock() {
+	rw.dbg.wait()
+	rw.lwm.ulock()
+	rw.dbg.acquired()
+}
+
+// TryULock makes one attempt to lock the mutex in upgradable mode. It returns true on succeess and false otherwise.
+func (rw *RWMutex) TryULock() bool {
+	if rw.lwm.tryULock() {
+		rw.dbg.acquired()
+		return true
+	}
+	return false
+}
+
+// UUnlock releases upgradable lock. It panics on an error, or if the mutex is not locked in upgradable mode.
+func (rw *RWMutex) UUnlock() {
+	rw.dbg.released()
+	rw.lwm.uunlock()
+}
+
+// UpgradeToLock converts upgradable lock into exclusive lock.
+// It waits for all readers to release the mutex. New readers and writers
+// can't acquire the mutex in meanwhile, so the data, observed by the caller
+// under the upgradable lock, stays unchanged.
+// The caller must hold the mutex in upgradable mode. The mutex must be released with Unlock.
+func (rw *RWMutex) UpgradeToLock() {
+	rw.lwm.upgrade()
+}
+
+// DowngradeToRLock atomically converts exclusive lock into a read lock.
+// Waiting readers may acquire the mutex after that, if the policy allows it.
+// The mutex must be released with RUnlock.
+func (rw *RWMutex) DowngradeToRLock() {
+	rw.lwm.downgrade()
+}
+
+// debugResult records the result of a timed lock operation in the debug registry.
+func (rw *RWMutex) debugResult(locked bool) bool {
+	if locked {
+		rw.dbg.acquired()
+	} else {
+		rw.dbg.cancel()
+	}
+	return locked
+}
+
+// Stats returns contention metrics of the mutex. Read and write locks are counted together.
+// It returns an error, if the mutex was opened with stats disabled. See SetStatsEnabled for details.
+func (rw *RWMutex) Stats() (Stats, error) {
+	return rw.lwm.stats.stats()
+}
+
+// Attached returns the number of alive processes, which opened the mutex with O_ATTACH or O_AUTODESTROY.
+// It returns an error, if the mutex was opened without these flags.
+func (rw *RWMutex) Attached() (int, error) {
+	return rw.attach.Count()
+}
+
 // Close closes shared state of the mutex.
+// If the mutex was opened with O_AUTODESTROY, and it was the last attached process, the mutex is destroyed.
 func (rw *RWMutex) Close() error {
-	e1, e2 := closeRWWaiters(rw.wR, rw.wW), rw.region.Close()
+	if rw.region == nil {
+		return nil
+	}
+	e1, e2, e3 := closeRWWaiters(rw.waiters), rw.region.Close(), rw.lwm.stats.close()
+	e4 := rw.attach.Close(func() error {
+		return destroyRWMutex(rw.name)
+	})
 	if e1 != nil {
 		return e1
 	}
 	if e2 != nil {
 		return e2
 	}
-	return nil
+	if e3 != nil {
+		return e3
+	}
+	return e4
 }
 
 // Destroy closes the mutex and removes it permanently.
 func (rw *RWMutex) Destroy() error {
+	if rw.region == nil {
+		return nil
+	}
 	if err := rw.Close(); err != nil {
 		return errors.Wrap(err, "failed to close shared state")
 	}
@@ -96,19 +283,31 @@ func (rw *RWMutex) Destroy() error {
 
 // DestroyRWMutex permanently removes mutex with the given name.
 func DestroyRWMutex(name string) error {
+	e1, e2 := destroyRWMutex(name), destroyAttachment(name, "rw")
+	if e1 != nil {
+		return e1
+	}
+	return e2
+}
+
+// destroyRWMutex removes the objects of the mutex except its attach table,
+// which is removed by the last attached process itself.
+func destroyRWMutex(name string) error {
 	e1 := shm.DestroyMemoryObject(mutexSharedStateName(name, "rw"))
 	e2 := destroyRWWaiters(name)
+	e3 := destroyStats(name, "rw")
 	if e1 != nil {
 		return errors.Wrap(e1, "failed to destroy shared state")
 	}
 	if e2 != nil {
 		return e2
 	}
-	return nil
+	return e3
 }
 
 // RLocker returns a Locker interface that implements
 // the Lock and Unlock methods by calling rw.RLock and rw.RUnlock.
+// The returned locker also implements TimedIPCLocker via rw.RLockTimeout.
 func (rw *RWMutex) RLocker() IPCLocker {
 	return (*rlocker)(rw)
 }
@@ -119,39 +318,6 @@ func (r *rlocker) Lock()        { (*RWMutex)(r).RLock() }
 func (r *rlocker) Unlock()      { (*RWMutex)(r).RUnlock() }
 func (r *rlocker) Close() error { return (*RWMutex)(r).Close() }
 
-func makeRWMWaiters(name string, flag int, perm os.FileMode) (waitWaker, waitWaker, error) {
-	rSema, err := NewSemaphore(name+".rs", flag, perm, 0)
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to create r/sema")
-	}
-	wSema, err := NewSemaphore(name+".ws", flag, perm, 0)
-	if err != nil {
-		rSema.Close()
-		DestroySemaphore(name + ".rs")
-		return nil, nil, errors.Wrap(err, "failed to create w/sema")
-	}
-	return newSemaWaiter(rSema), newSemaWaiter(wSema), nil
-}
-
-func closeRWWaiters(wR, wW waitWaker) error {
-	sR, sW := wR.(*semaWaiter).s, wW.(*semaWaiter).s
-	e1, e2 := sR.Close(), sW.Close()
-	if e1 != nil {
-		return errors.Wrap(e1, "failed to close r/sema")
-	}
-	if e2 != nil {
-		return errors.Wrap(e2, "failed to close w/sema")
-	}
-	return nil
-}
-
-func destroyRWWaiters(name string) error {
-	e1, e2 := DestroySemaphore(name+".rs"), DestroySemaphore(name+".ws")
-	if e1 != nil {
-		return errors.Wrap(e1, "failed to deatroy r/sema")
-	}
-	if e2 != nil {
-		return errors.Wrap(e2, "failed to deatroy w/sema")
-	}
-	return nil
+func (r *rlocker) LockTimeout(timeout time.Duration) bool {
+	return (*RWMutex)(r).RLockTimeout(timeout)
 }
@@ -0,0 +1,78 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build linux,!sysv_sema_linux
+
+package sync
+
+import (
+	"os"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/mmf"
+)
+
+const (
+	// futex-based waiters are placed into mutex's shared state right after lwRWMutex state.
+	rwmWaitersStateSize = 3 * futexSemaStateSize
+	// RWMutexSize is the number of bytes occupied by a RWMutex placed in a memory region.
+	RWMutexSize = lwRWMStateSize + rwmWaitersStateSize
+)
+
+// NewRWMutexAt returns a phase-fair RWMutex placed in the region at the given offset.
+// Such a mutex does not own any named objects, so Close and Destroy do nothing,
+// and the region must not be closed while the mutex is in use.
+//	region - memory region, which holds the mutex. it must be writable.
+//	offset - offset of the mutex in the region. it must be 8-byte aligned.
+//	init - if true, the memory is initialized as an unlocked mutex.
+// It is available on linux only, unless 'sysv_sema_linux' tag is set.
+func NewRWMutexAt(region *mmf.MemoryRegion, offset int, init bool) (*RWMutex, error) {
+	return NewRWMutexPolicyAt(region, offset, init, RWMutexPhaseFair)
+}
+
+// NewRWMutexPolicyAt returns a RWMutex with the given policy placed in the region at the given offset.
+// If init is false, the policy argument is ignored. See NewRWMutexAt for details.
+func NewRWMutexPolicyAt(region *mmf.MemoryRegion, offset int, init bool, policy RWMutexPolicy) (*RWMutex, error) {
+	if err := ensureRWMutexPolicy(policy); err != nil {
+		return nil, err
+	}
+	data, err := regionPointerAt(region, offset, RWMutexSize, 8)
+	if err != nil {
+		return nil, err
+	}
+	waiters, err := makeRWMWaiters("", 0, 0, data, init)
+	if err != nil {
+		return nil, err
+	}
+	result := &RWMutex{waiters: waiters, lwm: newRWLightweightMutex(data, waiters)}
+	if init {
+		result.lwm.init(policy)
+	}
+	return result, nil
+}
+
+// makeRWMWaiters places reader, writer, and upgrader semaphores into the shared state.
+// the gate mutex of upgradable lockers uses its state word as a futex.
+func makeRWMWaiters(name string, flag int, perm os.FileMode, state unsafe.Pointer, created bool) (rwmWaiters, error) {
+	rSema := newFutexSema(unsafe.Pointer(uintptr(state) + lwRWMStateSize))
+	wSema := newFutexSema(unsafe.Pointer(uintptr(state) + lwRWMStateSize + futexSemaStateSize))
+	uSema := newFutexSema(unsafe.Pointer(uintptr(state) + lwRWMStateSize + 2*futexSemaStateSize))
+	if created {
+		rSema.init(0)
+		wSema.init(0)
+		uSema.init(0)
+	}
+	return rwmWaiters{
+		r: rSema,
+		w: wSema,
+		u: uSema,
+		g: &futex{ptr: unsafe.Pointer(uintptr(state) + lwRWMGateOffset)},
+	}, nil
+}
+
+func closeRWWaiters(waiters rwmWaiters) error {
+	return nil
+}
+
+func destroyRWWaiters(name string) error {
+	return nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build linux,!sysv_sema_linux
+
+package sync
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRWMutexAt(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(shm.DestroyMemoryObject(testMemObj)) {
+		return
+	}
+	defer shm.DestroyMemoryObject(testMemObj)
+	region, err := createMemoryRegionSimple(os.O_CREATE|os.O_EXCL|os.O_RDWR, mmf.MEM_READWRITE, 128, 0)
+	if !a.NoError(err) {
+		return
+	}
+	defer region.Close()
+	_, err = NewRWMutexAt(region, 4, true)
+	a.Error(err)
+	_, err = NewRWMutexAt(region, 128-RWMutexSize+8, true)
+	a.Error(err)
+	m, err := NewRWMutexPolicyAt(region, 8, true, RWMutexWriterPreferring)
+	if !a.NoError(err) {
+		return
+	}
+	m2, err := NewRWMutexAt(region, 8, false)
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(RWMutexWriterPreferring, m2.Policy())
+	m.RLock()
+	a.True(m2.TryRLock())
+	a.False(m2.LockTimeout(time.Millisecond * 10))
+	m2.RUnlock()
+	m.RUnlock()
+	a.True(m2.TryLock())
+	a.False(m.TryRLock())
+	m2.Unlock()
+	a.NoError(m.Destroy())
+	a.NoError(m2.Destroy())
+}
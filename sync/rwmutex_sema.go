@@ -0,0 +1,60 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build !linux sysv_sema_linux
+
+package sync
+
+import (
+	"os"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// semaphore-based waiters do not keep anything in mutex's shared state.
+	rwmWaitersStateSize = 0
+)
+
+var rwmSemaSuffixes = [...]string{".rs", ".ws", ".us", ".gs"}
+
+func makeRWMWaiters(name string, flag int, perm os.FileMode, unused unsafe.Pointer, created bool) (rwmWaiters, error) {
+	var semas [len(rwmSemaSuffixes)]*Semaphore
+	for i, suffix := range rwmSemaSuffixes {
+		s, err := openSemaphore(name+suffix, flag, perm, 0)
+		if err != nil {
+			for j := 0; j < i; j++ {
+				semas[j].Close()
+				DestroySemaphore(name + rwmSemaSuffixes[j])
+			}
+			return rwmWaiters{}, errors.Wrapf(err, "failed to create %s sema", suffix)
+		}
+		semas[i] = s
+	}
+	return rwmWaiters{
+		r: newSemaWaiter(semas[0]),
+		w: newSemaWaiter(semas[1]),
+		u: newSemaWaiter(semas[2]),
+		g: newSemaWaiter(semas[3]),
+	}, nil
+}
+
+func closeRWWaiters(waiters rwmWaiters) error {
+	var result error
+	for i, ww := range []waitWaker{waiters.r, waiters.w, waiters.u, waiters.g} {
+		if err := ww.(*semaWaiter).s.Close(); err != nil && result == nil {
+			result = errors.Wrapf(err, "failed to close %s sema", rwmSemaSuffixes[i])
+		}
+	}
+	return result
+}
+
+func destroyRWWaiters(name string) error {
+	var result error
+	for _, suffix := range rwmSemaSuffixes {
+		if err := DestroySemaphore(name + suffix); err != nil && result == nil {
+			result = errors.Wrapf(err, "failed to destroy %s sema", suffix)
+		}
+	}
+	return result
+}
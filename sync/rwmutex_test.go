@@ -7,7 +7,11 @@ import (
 	"math/rand"
 	"os"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func rwMutexCtor(name string, flag int, perm os.FileMode) (IPCLocker, error) {
@@ -70,6 +74,217 @@ func TestRWMutexPanicsOnDoubleRUnlock(t *testing.T) {
 	testLockerTwiceUnlock(t, rwRMutexCtor, rwMutexDtor)
 }
 
+func TestRWMutexLockTimeout(t *testing.T) {
+	testLockerLockTimeout(t, "rw", rwMutexCtor, rwMutexDtor)
+}
+
+func TestRWMutexLockTimeout2(t *testing.T) {
+	testLockerLockTimeout2(t, "rw", rwMutexCtor, rwMutexDtor)
+}
+
+func TestRWMutexRLockTimeout(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyRWMutex(testLockerName)) {
+		return
+	}
+	m, err := NewRWMutex(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(m.Destroy())
+	}()
+	m.Lock()
+	a.False(m.TryRLock())
+	a.False(m.TryLock())
+	a.False(m.RLockTimeout(time.Millisecond * 50))
+	a.False(m.LockTimeout(time.Millisecond * 50))
+	ch := make(chan bool)
+	go func() {
+		ch <- m.RLockTimeout(time.Second)
+	}()
+	<-time.After(time.Millisecond * 50)
+	m.Unlock()
+	if !a.True(<-ch) {
+		return
+	}
+	a.True(m.TryRLock())
+	a.False(m.TryLock())
+	a.False(m.LockTimeout(time.Millisecond * 50))
+	m.RUnlock()
+	m.RUnlock()
+	a.True(m.TryLock())
+	m.Unlock()
+}
+
+func testRWMutexPolicy(t *testing.T, policy RWMutexPolicy, readerMustWait bool) {
+	a := assert.New(t)
+	if !a.NoError(DestroyRWMutex(testLockerName)) {
+		return
+	}
+	m, err := NewRWMutexPolicy(testLockerName, os.O_CREATE|os.O_EXCL, 0666, policy)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(m.Destroy())
+	}()
+	// the policy of an existing mutex must be preserved.
+	m2, err := NewRWMutex(testLockerName, 0, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m2.Close()
+	if !a.Equal(policy, m2.Policy()) {
+		return
+	}
+	m.RLock()
+	ch := make(chan struct{})
+	go func() {
+		m2.Lock()
+		m2.Unlock()
+		ch <- struct{}{}
+	}()
+	// let the writer start waiting.
+	<-time.After(time.Millisecond * 50)
+	if readerMustWait {
+		a.False(m2.TryRLock())
+		a.False(m2.RLockTimeout(time.Millisecond * 50))
+	} else if a.True(m2.TryRLock()) {
+		m2.RUnlock()
+	}
+	m.RUnlock()
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Error("writer has not acquired the lock")
+	}
+}
+
+func TestRWMutexPhaseFair(t *testing.T) {
+	testRWMutexPolicy(t, RWMutexPhaseFair, true)
+}
+
+func TestRWMutexReaderPreferring(t *testing.T) {
+	testRWMutexPolicy(t, RWMutexReaderPreferring, false)
+}
+
+func TestRWMutexWriterPreferring(t *testing.T) {
+	testRWMutexPolicy(t, RWMutexWriterPreferring, true)
+}
+
+func TestRWMutexWriterPreferringOrder(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyRWMutex(testLockerName)) {
+		return
+	}
+	m, err := NewRWMutexPolicy(testLockerName, os.O_CREATE|os.O_EXCL, 0666, RWMutexWriterPreferring)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(m.Destroy())
+	}()
+	var order []string
+	var wg sync.WaitGroup
+	m.Lock()
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		m.RLock()
+		order = append(order, "r")
+		m.RUnlock()
+	}()
+	<-time.After(time.Millisecond * 50)
+	go func() {
+		defer wg.Done()
+		m.Lock()
+		order = append(order, "w")
+		m.Unlock()
+	}()
+	<-time.After(time.Millisecond * 50)
+	m.Unlock()
+	wg.Wait()
+	a.Equal([]string{"w", "r"}, order)
+}
+
+func TestRWMutexUpgrade(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyRWMutex(testLockerName)) {
+		return
+	}
+	m, err := NewRWMutex(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(m.Destroy())
+	}()
+	m.ULock()
+	// upgradable lock is shared with readers, but excludes writers and other upgradable lockers.
+	if !a.True(m.TryRLock()) {
+		return
+	}
+	a.False(m.TryULock())
+	a.False(m.TryLock())
+	var value int32
+	ch := make(chan int, 2)
+	go func() {
+		m.Lock()
+		atomic.StoreInt32(&value, 2)
+		m.Unlock()
+		ch <- 2
+	}()
+	<-time.After(time.Millisecond * 50)
+	go func() {
+		m.UpgradeToLock()
+		atomic.StoreInt32(&value, 1)
+		ch <- 1
+		// the mutex must be locked exclusively after the upgrade.
+		a.False(m.TryRLock())
+		m.DowngradeToRLock()
+		a.Equal(int32(1), atomic.LoadInt32(&value))
+		m.RUnlock()
+	}()
+	<-time.After(time.Millisecond * 50)
+	// the upgrading reader waits for us and prevents new readers from entering.
+	a.False(m.TryRLock())
+	a.Equal(int32(0), atomic.LoadInt32(&value))
+	m.RUnlock()
+	a.Equal(1, <-ch)
+	a.Equal(2, <-ch)
+	a.Equal(int32(2), atomic.LoadInt32(&value))
+	a.True(m.TryULock())
+	m.UUnlock()
+	a.True(m.TryLock())
+	m.Unlock()
+}
+
+func TestRWMutexPanicsOnUnlockedUpgrade(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyRWMutex(testLockerName)) {
+		return
+	}
+	m, err := NewRWMutex(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(m.Destroy())
+	}()
+	a.Panics(func() {
+		m.UpgradeToLock()
+	})
+	a.Panics(func() {
+		m.UUnlock()
+	})
+	m.RLock()
+	a.Panics(func() {
+		m.DowngradeToRLock()
+	})
+	m.RUnlock()
+}
+
 func ExampleRWMutex() {
 	const (
 		writers = 4
@@ -0,0 +1,87 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build linux,!sysv_sema_linux
+
+package sync
+
+import (
+	"os"
+	"time"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/common"
+	"github.com/nxgtw/go-ipc/internal/helper"
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
+
+	"github.com/pkg/errors"
+)
+
+// semaphore is a futex-based semaphore.
+// Unlike sysV semaphores, it does not need temporary files for ftok,
+// and it is not limited by the kernel's SEMMNI value.
+type semaphore struct {
+	name   string
+	region *mmf.MemoryRegion
+	fs     *futexSema
+	stats  *statsRecorder
+}
+
+// newSemaphore creates a new futex-based semaphore with the given name.
+func newSemaphore(name string, flag int, perm os.FileMode, initial int) (*semaphore, error) {
+	if err := ensureOpenFlags(flag); err != nil {
+		return nil, err
+	}
+	region, created, err := helper.CreateWritableRegion(semaSharedStateName(name), flag, perm, futexSemaStateSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create shared state")
+	}
+	result := &semaphore{
+		name:   name,
+		region: region,
+		fs:     newFutexSema(allocator.ByteSliceData(region.Data())),
+	}
+	if created {
+		result.fs.init(int32(initial))
+	}
+	return result, nil
+}
+
+func (s *semaphore) signal(count int) {
+	if _, err := s.fs.wake(int32(count)); err != nil {
+		panic(err)
+	}
+}
+
+func (s *semaphore) wait() {
+	if err := s.fs.wait(0, -1); err != nil {
+		panic(err)
+	}
+}
+
+func (s *semaphore) waitTimeout(timeout time.Duration) bool {
+	err := s.fs.wait(0, timeout)
+	if err == nil {
+		return true
+	}
+	if common.IsTimeoutErr(err) {
+		return false
+	}
+	panic(err)
+}
+
+func (s *semaphore) close() error {
+	return s.region.Close()
+}
+
+// destroySemaphore permanently removes semaphore with the given name.
+func destroySemaphore(name string) error {
+	if err := shm.DestroyMemoryObject(semaSharedStateName(name)); err != nil {
+		return errors.Wrap(err, "failed to destroy memory object")
+	}
+	return nil
+}
+
+func semaSharedStateName(name string) string {
+	return name + ".sema"
+}
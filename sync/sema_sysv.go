@@ -0,0 +1,16 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build darwin freebsd linux
+
+package sync
+
+const (
+	cSemUndo = 0x1000
+)
+
+// sembuf is a sysV semaphore operation description used by semop and semtimedop.
+type sembuf struct {
+	semnum uint16
+	semop  int16
+	semflg int16
+}
@@ -1,6 +1,6 @@
 // Copyright 2016 Aleksandr Demakin. All rights reserved.
 
-// +build darwin freebsd linux
+// +build darwin freebsd linux,sysv_sema_linux
 
 package sync
 
@@ -12,20 +12,11 @@ import (
 	"github.com/pkg/errors"
 )
 
-const (
-	cSemUndo = 0x1000
-)
-
-type sembuf struct {
-	semnum uint16
-	semop  int16
-	semflg int16
-}
-
 // semaphore is a sysV semaphore.
 type semaphore struct {
-	name string
-	id   int
+	name  string
+	id    int
+	stats *statsRecorder
 }
 
 // newSemaphore creates a new sysV semaphore with the given name.
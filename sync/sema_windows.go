@@ -15,6 +15,7 @@ import (
 // on windows it uses system semaphore object.
 type semaphore struct {
 	handle windows.Handle
+	stats  *statsRecorder
 }
 
 func newSemaphore(name string, flag int, perm os.FileMode, initial int) (*semaphore, error) {
@@ -19,7 +19,8 @@ const (
 // which can be used to control access to a shared resource.
 // It provides access to actual OS semaphore primitive via:
 //	CreateSemaprore on windows
-//	semget on unix
+//	futex on linux (semget, if built with 'sysv_sema_linux' tag)
+//	semget on darwin and freebsd
 type Semaphore semaphore
 
 // NewSemaphore creates new semaphore with the given name.
@@ -28,6 +29,20 @@ type Semaphore semaphore
 //	perm - object's permission bits.
 //	initial - this value will be added to the semaphore's value, if it was created.
 func NewSemaphore(name string, flag int, perm os.FileMode, initial int) (*Semaphore, error) {
+	result, err := openSemaphore(name, flag, perm, initial)
+	if err != nil {
+		return nil, err
+	}
+	if result.stats, err = openStats(name, "sem", perm); err != nil {
+		result.Close()
+		return nil, err
+	}
+	return result, nil
+}
+
+// openSemaphore creates a semaphore without stats.
+// it is used by other primitives, which use a semaphore internally.
+func openSemaphore(name string, flag int, perm os.FileMode, initial int) (*Semaphore, error) {
 	result, err := newSemaphore(name, flag, perm, initial)
 	if err != nil {
 		return nil, err
@@ -42,24 +57,40 @@ func (s *Semaphore) Signal(count int) {
 
 // Wait decrements the value of semaphore variable by -1, and blocks if the value becomes negative.
 func (s *Semaphore) Wait() {
+	start := s.stats.start()
 	(*semaphore)(s).wait()
+	s.stats.waited(start, true)
 }
 
 // Close closes the semaphore.
 func (s *Semaphore) Close() error {
-	return (*semaphore)(s).close()
+	e1, e2 := (*semaphore)(s).close(), s.stats.close()
+	if e1 != nil {
+		return e1
+	}
+	return e2
 }
 
 // WaitTimeout decrements the value of semaphore variable by 1.
 // If the value becomes negative, it waites for not longer than timeout.
 // On darwin and freebsd this func has some side effects, see sema_timed_bsd.go for details.
 func (s *Semaphore) WaitTimeout(timeout time.Duration) bool {
-	return (*semaphore)(s).waitTimeout(timeout)
+	start := s.stats.start()
+	return s.stats.waited(start, (*semaphore)(s).waitTimeout(timeout))
+}
+
+// Stats returns wait time metrics of the semaphore.
+// It returns an error, if the semaphore was opened with stats disabled. See SetStatsEnabled for details.
+func (s *Semaphore) Stats() (Stats, error) {
+	return s.stats.stats()
 }
 
 // DestroySemaphore removes the semaphore permanently.
 func DestroySemaphore(name string) error {
-	return destroySemaphore(name)
+	if err := destroySemaphore(name); err != nil {
+		return err
+	}
+	return destroyStats(name, "sem")
 }
 
 type semaWaiter struct {
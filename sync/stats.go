@@ -0,0 +1,207 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/helper"
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// StatsBucketCount is the number of buckets in a wait time histogram.
+	StatsBucketCount = 8
+)
+
+var (
+	// StatsBucketBounds are upper bounds of wait time histogram buckets.
+	// The last bucket counts all waits longer, than the last bound.
+	// Buckets are not cumulative, so a wait is counted only in one bucket.
+	StatsBucketBounds = [StatsBucketCount - 1]time.Duration{
+		10 * time.Microsecond,
+		100 * time.Microsecond,
+		time.Millisecond,
+		10 * time.Millisecond,
+		100 * time.Millisecond,
+		time.Second,
+		10 * time.Second,
+	}
+
+	statsEnabled int32
+
+	errStatsDisabled = errors.New("stats are not enabled for the object")
+)
+
+// LockStats contains contention and wait time metrics of a synchronization object.
+type LockStats struct {
+	// Acquisitions is the number of successful lock or wait operations.
+	Acquisitions uint64
+	// Contended is the number of operations, which had to wait.
+	// For Semaphore and Cond every wait is counted as contended.
+	Contended uint64
+	// Timeouts is the number of timed operations, which failed.
+	Timeouts uint64
+	// WaitTime is the total time spent by contended operations.
+	WaitTime time.Duration
+	// WaitHistogram contains the number of contended operations
+	// by their duration. See StatsBucketBounds for the buckets.
+	WaitHistogram [StatsBucketCount]uint64
+}
+
+// Stats contains metrics of a synchronization object.
+type Stats struct {
+	// Shared contains metrics of all processes, which opened the object with stats enabled.
+	// They are kept in shared memory, and persist, until the object is destroyed.
+	Shared LockStats
+	// Process contains metrics, collected via this instance of the object in the current process.
+	Process LockStats
+}
+
+// SetStatsEnabled enables or disables stats for FutexMutex, RWMutex, Semaphore and Cond.
+// It affects only objects, created or opened after the call. Objects with stats enabled
+// keep their shared counters in a separate memory object, which is removed, when the object is destroyed.
+// Stats are disabled by default.
+func SetStatsEnabled(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+	atomic.StoreInt32(&statsEnabled, value)
+}
+
+// StatsEnabled returns true, if new objects are created with stats enabled.
+func StatsEnabled() bool {
+	return atomic.LoadInt32(&statsEnabled) != 0
+}
+
+// lockCounters is a set of counters, which is kept both in shared memory and per process.
+// all fields are updated atomically.
+type lockCounters struct {
+	acquisitions uint64
+	contended    uint64
+	timeouts     uint64
+	waitTime     uint64
+	histogram    [StatsBucketCount]uint64
+}
+
+const lockCountersSize = int(unsafe.Sizeof(lockCounters{}))
+
+func (lc *lockCounters) acquired() {
+	atomic.AddUint64(&lc.acquisitions, 1)
+}
+
+func (lc *lockCounters) waited(d time.Duration, ok bool) {
+	if ok {
+		atomic.AddUint64(&lc.acquisitions, 1)
+	} else {
+		atomic.AddUint64(&lc.timeouts, 1)
+	}
+	atomic.AddUint64(&lc.contended, 1)
+	atomic.AddUint64(&lc.waitTime, uint64(d))
+	bucket := 0
+	for bucket < len(StatsBucketBounds) && d > StatsBucketBounds[bucket] {
+		bucket++
+	}
+	atomic.AddUint64(&lc.histogram[bucket], 1)
+}
+
+func (lc *lockCounters) load() LockStats {
+	result := LockStats{
+		Acquisitions: atomic.LoadUint64(&lc.acquisitions),
+		Contended:    atomic.LoadUint64(&lc.contended),
+		Timeouts:     atomic.LoadUint64(&lc.timeouts),
+		WaitTime:     time.Duration(atomic.LoadUint64(&lc.waitTime)),
+	}
+	for i := range lc.histogram {
+		result.WaitHistogram[i] = atomic.LoadUint64(&lc.histogram[i])
+	}
+	return result
+}
+
+// statsRecorder updates shared and local counters of an object.
+// all its methods can be called on a nil recorder, if stats are disabled.
+type statsRecorder struct {
+	// local must be the first field to be 64-bit aligned on 32-bit platforms.
+	local  lockCounters
+	shared *lockCounters
+	region *mmf.MemoryRegion
+}
+
+// openStats opens shared counters of the object, if stats are enabled.
+// it returns nil recorder, if they are not.
+func openStats(name, typ string, perm os.FileMode) (*statsRecorder, error) {
+	if !StatsEnabled() {
+		return nil, nil
+	}
+	region, _, err := helper.CreateWritableRegion(statsStateName(name, typ), os.O_CREATE, perm, lockCountersSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create shared stats")
+	}
+	return &statsRecorder{
+		shared: (*lockCounters)(allocator.ByteSliceData(region.Data())),
+		region: region,
+	}, nil
+}
+
+func destroyStats(name, typ string) error {
+	if err := shm.DestroyMemoryObject(statsStateName(name, typ)); err != nil {
+		return errors.Wrap(err, "failed to destroy shared stats")
+	}
+	return nil
+}
+
+func statsStateName(name, typ string) string {
+	return name + ".st" + typ
+}
+
+func (sr *statsRecorder) acquired() {
+	if sr == nil {
+		return
+	}
+	sr.local.acquired()
+	sr.shared.acquired()
+}
+
+// start returns the start time of a wait operation.
+func (sr *statsRecorder) start() time.Time {
+	if sr == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// waited records a wait operation, which has started at the given time.
+func (sr *statsRecorder) waited(start time.Time, ok bool) bool {
+	if sr == nil {
+		return ok
+	}
+	d := time.Since(start)
+	sr.local.waited(d, ok)
+	sr.shared.waited(d, ok)
+	return ok
+}
+
+func (sr *statsRecorder) stats() (Stats, error) {
+	if sr == nil {
+		return Stats{}, errStatsDisabled
+	}
+	return Stats{Shared: sr.shared.load(), Process: sr.local.load()}, nil
+}
+
+func (sr *statsRecorder) close() error {
+	if sr == nil {
+		return nil
+	}
+	if err := sr.region.Close(); err != nil {
+		return errors.Wrap(err, "failed to close shared stats")
+	}
+	return nil
+}
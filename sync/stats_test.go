@@ -0,0 +1,173 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testStatsObjName = "go-ipc.sync-test.stats"
+)
+
+func histogramSum(s LockStats) uint64 {
+	var result uint64
+	for _, v := range s.WaitHistogram {
+		result += v
+	}
+	return result
+}
+
+func TestStatsDisabled(t *testing.T) {
+	a := assert.New(t)
+	SetStatsEnabled(false)
+	a.False(StatsEnabled())
+	if !a.NoError(DestroyRWMutex(testStatsObjName)) {
+		return
+	}
+	m, err := NewRWMutex(testStatsObjName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m.Destroy()
+	m.Lock()
+	m.Unlock()
+	_, err = m.Stats()
+	a.Error(err)
+}
+
+func TestRWMutexStats(t *testing.T) {
+	a := assert.New(t)
+	SetStatsEnabled(true)
+	defer SetStatsEnabled(false)
+	if !a.NoError(DestroyRWMutex(testStatsObjName)) {
+		return
+	}
+	m, err := NewRWMutex(testStatsObjName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m.Destroy()
+	m2, err := NewRWMutex(testStatsObjName, 0, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m2.Close()
+	m.Lock()
+	a.False(m2.TryRLock())
+	a.False(m2.RLockTimeout(time.Millisecond * 20))
+	go func() {
+		time.Sleep(time.Millisecond * 20)
+		m.Unlock()
+	}()
+	m2.Lock()
+	m2.Unlock()
+	st, err := m.Stats()
+	if !a.NoError(err) {
+		return
+	}
+	st2, err := m2.Stats()
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(uint64(1), st.Process.Acquisitions)
+	a.Equal(uint64(0), st.Process.Contended)
+	a.Equal(uint64(1), st2.Process.Acquisitions)
+	a.Equal(uint64(2), st2.Process.Contended)
+	a.Equal(uint64(1), st2.Process.Timeouts)
+	a.Equal(uint64(2), histogramSum(st2.Process))
+	a.True(st2.Process.WaitTime >= time.Millisecond*20)
+	a.Equal(st2.Shared, st.Shared)
+	a.Equal(uint64(2), st.Shared.Acquisitions)
+	a.Equal(uint64(2), st.Shared.Contended)
+	a.Equal(uint64(1), st.Shared.Timeouts)
+}
+
+func TestStatsDestroyed(t *testing.T) {
+	a := assert.New(t)
+	SetStatsEnabled(true)
+	defer SetStatsEnabled(false)
+	if !a.NoError(DestroySemaphore(testStatsObjName)) {
+		return
+	}
+	s, err := NewSemaphore(testStatsObjName, os.O_CREATE|os.O_EXCL, 0666, 1)
+	if !a.NoError(err) {
+		return
+	}
+	s.Wait()
+	a.NoError(s.Close())
+	a.NoError(DestroySemaphore(testStatsObjName))
+	s, err = NewSemaphore(testStatsObjName, os.O_CREATE|os.O_EXCL, 0666, 0)
+	if !a.NoError(err) {
+		return
+	}
+	defer DestroySemaphore(testStatsObjName)
+	defer s.Close()
+	st, err := s.Stats()
+	if a.NoError(err) {
+		a.Equal(LockStats{}, st.Shared)
+	}
+}
+
+func TestSemaphoreStats(t *testing.T) {
+	a := assert.New(t)
+	SetStatsEnabled(true)
+	defer SetStatsEnabled(false)
+	if !a.NoError(DestroySemaphore(testStatsObjName)) {
+		return
+	}
+	s, err := NewSemaphore(testStatsObjName, os.O_CREATE|os.O_EXCL, 0666, 0)
+	if !a.NoError(err) {
+		return
+	}
+	defer DestroySemaphore(testStatsObjName)
+	defer s.Close()
+	a.False(s.WaitTimeout(time.Millisecond * 10))
+	s.Signal(1)
+	s.Wait()
+	st, err := s.Stats()
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(uint64(1), st.Process.Acquisitions)
+	a.Equal(uint64(2), st.Process.Contended)
+	a.Equal(uint64(1), st.Process.Timeouts)
+	a.Equal(uint64(2), histogramSum(st.Process))
+	a.Equal(st.Process, st.Shared)
+}
+
+func TestCondStats(t *testing.T) {
+	a := assert.New(t)
+	SetStatsEnabled(true)
+	defer SetStatsEnabled(false)
+	if !a.NoError(DestroyMutex(testStatsObjName)) || !a.NoError(DestroyCond(testStatsObjName)) {
+		return
+	}
+	l, err := NewMutex(testStatsObjName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer DestroyMutex(testStatsObjName)
+	defer l.Close()
+	c, err := NewCond(testStatsObjName, os.O_CREATE|os.O_EXCL, 0666, l)
+	if !a.NoError(err) {
+		return
+	}
+	defer c.Destroy()
+	l.Lock()
+	a.False(c.WaitTimeout(time.Millisecond * 10))
+	l.Unlock()
+	st, err := c.Stats()
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(uint64(0), st.Shared.Acquisitions)
+	a.Equal(uint64(1), st.Shared.Contended)
+	a.Equal(uint64(1), st.Shared.Timeouts)
+	a.True(st.Shared.WaitTime >= time.Millisecond*10)
+	a.Equal(uint64(1), histogramSum(st.Shared))
+}
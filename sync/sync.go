@@ -5,6 +5,10 @@ package sync
 import (
 	"os"
 	"time"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/mmf"
 
 	"github.com/pkg/errors"
 )
@@ -22,3 +26,17 @@ type waitWaker interface {
 	wake(count int32) (int, error)
 	wait(value int32, timeout time.Duration) error
 }
+
+// regionPointerAt returns a pointer to the object of the given size placed in the region at the offset.
+// it checks, that the object fits the region, and that it is properly aligned.
+func regionPointerAt(region *mmf.MemoryRegion, offset, size, align int) (unsafe.Pointer, error) {
+	data := region.Data()
+	if offset < 0 || offset+size > len(data) {
+		return nil, errors.Errorf("invalid offset %d for an object of size %d in a region of size %d", offset, size, len(data))
+	}
+	ptr := unsafe.Pointer(uintptr(allocator.ByteSliceData(data)) + uintptr(offset))
+	if uintptr(ptr)%uintptr(align) != 0 {
+		return nil, errors.Errorf("object at offset %d is not %d-byte aligned", offset, align)
+	}
+	return ptr, nil
+}
@@ -11,10 +11,11 @@ import (
 	"os"
 	"reflect"
 	"strconv"
+	"strings"
 
 	testutil "github.com/nxgtw/go-ipc/internal/test"
-	"bitbucket.org/avd/go-ipc/mmf"
-	"bitbucket.org/avd/go-ipc/shm"
+	"github.com/nxgtw/go-ipc/mmf"
+	"github.com/nxgtw/go-ipc/shm"
 )
 
 const (
@@ -44,8 +45,15 @@ func locate(path string) []string {
 	for i, name := range files {
 		files[i] = path + name
 	}
+	var tags []string
 	if defaultMutexType == "msysv" {
-		files = append([]string{`-tags="sysv_mutex_linux"`}, files...)
+		tags = append(tags, "sysv_mutex_linux")
+	}
+	if len(helperBuildTags) > 0 {
+		tags = append(tags, helperBuildTags)
+	}
+	if len(tags) > 0 {
+		files = append([]string{"-tags=" + strings.Join(tags, ",")}, files...)
 	}
 	return files
 }
@@ -150,6 +158,16 @@ func argsForCondWaitCommand(condName, lockerName, waitEvent string) []string {
 	)
 }
 
+func argsForCondWaitNCommand(readySema, condName, lockerName string, n int) []string {
+	return append(condProgArgs,
+		"waitn",
+		readySema,
+		condName,
+		lockerName,
+		strconv.Itoa(n),
+	)
+}
+
 // Event test program
 
 func argsForEventSetCommand(name string) []string {
@@ -27,7 +27,7 @@ var (
 )
 
 func sys_OpenEvent(name string, desiredAccess uint32, inheritHandle uint32) (windows.Handle, error) {
-	namep, err := windows.UTF16PtrFromString(name)
+	namep, err := windows.UTF16PtrFromString(common.Namespaced(name))
 	if err != nil {
 		return 0, err
 	}
@@ -43,7 +43,7 @@ func sys_OpenEvent(name string, desiredAccess uint32, inheritHandle uint32) (win
 }
 
 func sys_CreateEvent(name string, eventAttrs *windows.SecurityAttributes, manualReset uint32, initialState uint32) (handle windows.Handle, err error) {
-	namep, err := windows.UTF16PtrFromString(name)
+	namep, err := windows.UTF16PtrFromString(common.Namespaced(name))
 	if err != nil {
 		return 0, err
 	}
@@ -88,7 +88,7 @@ func openOrCreateEvent(name string, flag int, initial int) (windows.Handle, erro
 }
 
 func sys_CreateSemaphore(name string, initial, maximum int, attrs *windows.SecurityAttributes) (windows.Handle, error) {
-	namep, err := windows.UTF16PtrFromString(name)
+	namep, err := windows.UTF16PtrFromString(common.Namespaced(name))
 	if err != nil {
 		return 0, err
 	}
@@ -128,7 +128,7 @@ func sys_ReleaseSemaphore(h windows.Handle, count int) (int, error) {
 }
 
 func sys_OpenSemaphore(name string, desiredAccess uint32, inheritHandle uint32) (windows.Handle, error) {
-	namep, err := windows.UTF16PtrFromString(name)
+	namep, err := windows.UTF16PtrFromString(common.Namespaced(name))
 	if err != nil {
 		return 0, err
 	}
@@ -10,6 +10,13 @@ func gettid() (uint32, error) {
 	return mach_thread_self(), nil
 }
 
+// currentThreadID returns the system-wide id of the calling thread.
+// unlike mach_thread_self, it does not allocate a port right, which must be released.
+func currentThreadID() int64 {
+	id, _, _ := unix.RawSyscall(unix.SYS_THREAD_SELFID, 0, 0, 0)
+	return int64(id)
+}
+
 func killThread(port uint32) error {
 	_, _, err := unix.Syscall(unix.SYS___PTHREAD_KILL, uintptr(port), uintptr(unix.SIGUSR2), 0)
 	return err
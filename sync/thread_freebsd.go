@@ -22,6 +22,15 @@ func gettid() (int, error) {
 	return int(tid), nil
 }
 
+// currentThreadID returns the id of the calling thread.
+func currentThreadID() int64 {
+	tid, err := gettid()
+	if err != nil {
+		panic(err)
+	}
+	return int64(tid)
+}
+
 func killThread(tid int) error {
 	_, _, err := unix.Syscall(unix.SYS_THR_KILL, uintptr(tid), uintptr(unix.SIGUSR2), 0)
 	return err
@@ -0,0 +1,10 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import "golang.org/x/sys/windows"
+
+// currentThreadID returns the id of the calling thread.
+func currentThreadID() int64 {
+	return int64(windows.GetCurrentThreadId())
+}